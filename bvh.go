@@ -0,0 +1,135 @@
+package glvox
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	bvhLeafSize = 4
+)
+
+// bvh is a bounding volume hierarchy over the non-degenerate triangles of
+// a soup. Leaves hold up to bvhLeafSize triangles; inner nodes split the
+// longest axis of their triangle centroids at the median.
+type bvh struct {
+	tris []Tri
+	nodes []bvhNode
+}
+
+type bvhNode struct {
+	lo, hi Vec3
+	left int			// inner nodes: children are left and left+1
+	first, count int	// leaves: tris[first:first+count]
+}
+
+func newBVH(tris []Tri) *bvh {
+
+	b := new(bvh)
+	for i := range tris {
+		if !tris[i].degenerate() { b.tris = append(b.tris, tris[i]) }
+	}
+	if len(b.tris) == 0 { return b }
+
+	type task struct { node, first, count int }
+	b.nodes = append(b.nodes, bvhNode {})
+	stack := []task { { 0, 0, len(b.tris) } }
+
+	for len(stack) > 0 {
+		t := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		part := b.tris[t.first:t.first+t.count]
+		lo, hi := Bounds(part)
+		n := &b.nodes[t.node]
+		n.lo, n.hi = lo, hi
+
+		if t.count <= bvhLeafSize {
+			n.first, n.count = t.first, t.count
+			continue
+		}
+
+		clo, chi := centroid(&part[0]), centroid(&part[0])
+		for i := range part {
+			c := centroid(&part[i])
+			clo = Vec3 { min32(clo.X, c.X), min32(clo.Y, c.Y), min32(clo.Z, c.Z) }
+			chi = Vec3 { max32(chi.X, c.X), max32(chi.Y, c.Y), max32(chi.Z, c.Z) }
+		}
+
+		ext := chi.Minus(clo)
+		axis := func(p Vec3) float32 { return p.X }
+		if ext.Y > ext.X && ext.Y >= ext.Z {
+			axis = func(p Vec3) float32 { return p.Y }
+		} else if ext.Z > ext.X && ext.Z > ext.Y {
+			axis = func(p Vec3) float32 { return p.Z }
+		}
+
+		sort.Slice(part, func(i, j int) bool {
+			return axis(centroid(&part[i])) < axis(centroid(&part[j]))
+		})
+
+		left := len(b.nodes)
+		n.left = left
+		b.nodes = append(b.nodes, bvhNode {}, bvhNode {})
+
+		half := t.count / 2
+		stack = append(stack,
+			task { left, t.first, half },
+			task { left+1, t.first + half, t.count - half })
+	}
+
+	return b
+}
+
+func centroid(tri *Tri) Vec3 {
+	return tri[0].Plus(tri[1]).Plus(tri[2]).Mul(1.0/3.0)
+}
+
+// boxSqrDistance returns the squared distance of p to the box [lo, hi].
+func boxSqrDistance(p, lo, hi Vec3) float32 {
+	d := p.Clamp(lo, hi).Minus(p)
+	return d.Dot(d)
+}
+
+// nearest returns the signed squared distance of p to the closest triangle.
+// Among triangles at the same distance the one p lies furthest in front of
+// or behind decides the sign, which keeps edges and corners consistent.
+func (b *bvh) nearest(p Vec3) float32 {
+
+	best := float32(math.Inf(1))
+	bestPlane := float32(0)
+	if len(b.nodes) == 0 { return best }
+
+	stack := []int { 0 }
+	for len(stack) > 0 {
+		n := &b.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+
+		if boxSqrDistance(p, n.lo, n.hi) > abs(best) { continue }
+
+		if n.count > 0 {
+			for i := n.first; i < n.first+n.count; i++ {
+				tri := &b.tris[i]
+				d := tri.SqrDistance(p)
+				switch {
+				case abs(d) < abs(best) * (1 - 1e-6):
+					best, bestPlane = d, tri.planeDistance(p)
+				case abs(d) <= abs(best) * (1 + 1e-6):
+					if pd := tri.planeDistance(p); abs(pd) > abs(bestPlane) {
+						best, bestPlane = d, pd
+					}
+				}
+			}
+			continue
+		}
+
+		l, r := n.left, n.left+1
+		if boxSqrDistance(p, b.nodes[l].lo, b.nodes[l].hi) < boxSqrDistance(p, b.nodes[r].lo, b.nodes[r].hi) {
+			l, r = r, l
+		}
+		stack = append(stack, l, r)
+	}
+
+	if bestPlane < 0 { return -abs(best) }
+	return abs(best)
+}
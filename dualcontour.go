@@ -0,0 +1,227 @@
+package glvox
+
+import (
+	"math"
+)
+
+// DualContour extracts the surface of src over a lattice of size cells of
+// edge length cell starting at org. Every cell the surface passes through
+// gets one vertex minimizing the QEF of its hermite samples, so sharp edges
+// and corners survive. The signs are collected into an octree built top
+// down; with a Distancer source whole nodes far from the surface become
+// single leaves and are never sampled. Cells are then merged bottom up
+// along the octree while the merged QEF error stays below tolerance; zero
+// keeps every cell. The surface must lie inside the lattice.
+func DualContour(src Hermite, org Vec3, size Size, cell float32, tolerance float32) *Mesh {
+
+	n := [3]int { size.W + 1, size.H + 1, size.D + 1 }
+	corner := func(x, y, z int) Vec3 {
+		return org.Plus(Vec3 { float32(x), float32(y), float32(z) }.Mul(cell))
+	}
+
+	signs := dcSigns(src, n, corner, cell)
+	inside := func(x, y, z int) bool {
+		v, _ := signs.Get(x, y, z)
+		return v != 0
+	}
+
+	// hermite samples on all edges with a sign change, added to the four
+	// cells around each edge
+	type edge struct {
+		c [3]int
+		axis int
+		flip bool
+	}
+
+	var edges []edge
+	cells := make(map[[3]int]*qef)
+	signs.leaves(0, 0, 0, n[0], n[1], n[2], func(l leaf) {
+		if l.val == 0 { return }
+
+		leafSurface(l, n, func(c [3]int) {
+			for axis := 0; axis < 3; axis++ {
+				for _, dir := range []int { -1, 1 } {
+					o := c
+					o[axis] += dir
+					if o[axis] < 0 || o[axis] >= n[axis] || inside(o[0], o[1], o[2]) { continue }
+
+					e := edge { c, axis, dir > 0 }
+					if dir < 0 { e.c = o }
+
+					a, b := corner(c[0], c[1], c[2]), corner(o[0], o[1], o[2])
+					p, nrm := src.Intersect(a, b)
+
+					edges = append(edges, e)
+					for _, k := range dcAround(e.c, axis) {
+						if !dcValid(k, size) { continue }
+						q := cells[k]
+						if q == nil { q = new(qef); cells[k] = q }
+						q.add(p, nrm)
+					}
+				}
+			}
+		})
+	})
+
+	vertex := dcVertices(cells, corner, cell, tolerance)
+
+	mesh := new(Mesh)
+	index := make(map[*dcCluster]int)
+	vid := func(c *dcCluster) int {
+		if i, ok := index[c]; ok { return i }
+		i := len(mesh.Vertices)
+		mesh.Vertices = append(mesh.Vertices, c.pos)
+		index[c] = i
+		return i
+	}
+
+	for _, e := range edges {
+		var quad []int
+		for _, k := range dcAround(e.c, e.axis) {
+			c := vertex[k]
+			if c == nil { continue }
+			i := vid(c)
+			if len(quad) > 0 && (quad[len(quad)-1] == i || quad[0] == i) { continue }
+			quad = append(quad, i)
+		}
+
+		if !e.flip {
+			for i, j := 0, len(quad)-1; i < j; i, j = i+1, j-1 { quad[i], quad[j] = quad[j], quad[i] }
+		}
+		for i := 1; i+1 < len(quad); i++ {
+			mesh.Indices = append(mesh.Indices, quad[0], quad[i], quad[i+1])
+		}
+	}
+
+	mesh.smoothNormals()
+	return mesh
+}
+
+// dcSigns builds the octree of corner signs, 1 inside and 0 outside.
+func dcSigns(src Hermite, n [3]int, corner func(x, y, z int) Vec3, cell float32) *Octree {
+
+	max := n[0]; if n[1] > max { max = n[1] }; if n[2] > max { max = n[2] }
+	oct := NewOctree(max)
+	dist, _ := src.(Distancer)
+
+	type node struct { x, y, z, size int }
+	stack := []node { { 0, 0, 0, oct.Size } }
+	for len(stack) > 0 {
+		nd := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if nd.x >= n[0] || nd.y >= n[1] || nd.z >= n[2] { continue }
+
+		if nd.size == 1 {
+			if src.Inside(corner(nd.x, nd.y, nd.z)) { oct.Set(nd.x, nd.y, nd.z, 1) }
+			continue
+		}
+
+		if dist != nil {
+			h := float32(nd.size - 1) / 2
+			c := corner(nd.x, nd.y, nd.z).Plus(Vec3 { h, h, h }.Mul(cell))
+			r := (h * float32(math.Sqrt(3)) + 1) * cell
+			if d := dist.Distance(c); d > r || d < -r {
+				if d < 0 { oct.setNode(nd.x, nd.y, nd.z, nd.size, 1) }
+				continue
+			}
+		}
+
+		s := nd.size / 2
+		for off := 0; off < 8; off++ {
+			stack = append(stack, node {
+				nd.x + (off & 1) * s, nd.y + (off >> 1 & 1) * s, nd.z + (off >> 2) * s, s })
+		}
+	}
+
+	return oct
+}
+
+// leafSurface calls fn for the corners on the surface of l inside [0, n).
+func leafSurface(l leaf, n [3]int, fn func(c [3]int)) {
+
+	x1, y1, z1 := l.x + l.size, l.y + l.size, l.z + l.size
+	if x1 > n[0] { x1 = n[0] }; if y1 > n[1] { y1 = n[1] }; if z1 > n[2] { z1 = n[2] }
+
+	for z := l.z; z < z1; z++ {
+		for y := l.y; y < y1; y++ {
+			full := z == l.z || z == z1-1 || y == l.y || y == y1-1
+			for x := l.x; x < x1; x++ {
+				if !full && x > l.x && x < x1-1 { x = x1-2; continue }
+				fn([3]int { x, y, z })
+			}
+		}
+	}
+}
+
+// dcAround returns the four cells sharing the edge from c along axis,
+// counter-clockwise around the axis.
+func dcAround(c [3]int, axis int) (k [4][3]int) {
+	u, v := (axis+1) % 3, (axis+2) % 3
+	for i := range k { k[i] = c }
+	k[1][u]--
+	k[2][u]--; k[2][v]--
+	k[3][v]--
+	return
+}
+
+func dcValid(k [3]int, size Size) bool {
+	return k[0] >= 0 && k[0] < size.W && k[1] >= 0 && k[1] < size.H && k[2] >= 0 && k[2] < size.D
+}
+
+type dcCluster struct {
+	q qef
+	pos Vec3
+	ok bool
+}
+
+// dcVertices solves the cell QEFs and merges cells level by level along
+// the octree while all children merged and the error stays below
+// tolerance. It returns the cluster each cell ends up in.
+func dcVertices(cells map[[3]int]*qef, corner func(x, y, z int) Vec3,
+	cell float32, tolerance float32) map[[3]int]*dcCluster {
+
+	level := make(map[[3]int]*dcCluster)
+	for k, q := range cells {
+		c := &dcCluster { q: *q, ok: true }
+		c.pos, _ = c.q.solve(corner(k[0], k[1], k[2]), corner(k[0]+1, k[1]+1, k[2]+1))
+		level[k] = c
+	}
+
+	levels := []map[[3]int]*dcCluster { level }
+	tol := float64(tolerance * cell) * float64(tolerance * cell)
+	for l := 1; tolerance > 0 && len(level) > 1; l++ {
+		up := make(map[[3]int]*dcCluster)
+		for k, c := range level {
+			pk := [3]int { k[0] >> 1, k[1] >> 1, k[2] >> 1 }
+			p := up[pk]
+			if p == nil { p = &dcCluster { ok: true }; up[pk] = p }
+			p.q.merge(&c.q)
+			p.ok = p.ok && c.ok
+		}
+
+		merged := false
+		for k, p := range up {
+			if !p.ok { continue }
+			s := 1 << uint(l)
+			var err float64
+			p.pos, err = p.q.solve(corner(k[0]*s, k[1]*s, k[2]*s), corner((k[0]+1)*s, (k[1]+1)*s, (k[2]+1)*s))
+			p.ok = err <= tol
+			merged = merged || p.ok
+		}
+		if !merged { break }
+
+		levels = append(levels, up)
+		level = up
+	}
+
+	vertex := make(map[[3]int]*dcCluster)
+	for k, c := range levels[0] {
+		vertex[k] = c
+		for l := len(levels)-1; l > 0; l-- {
+			pk := [3]int { k[0] >> uint(l), k[1] >> uint(l), k[2] >> uint(l) }
+			if p := levels[l][pk]; p != nil && p.ok { vertex[k] = p; break }
+		}
+	}
+
+	return vertex
+}
@@ -0,0 +1,86 @@
+package glvox
+
+import (
+	"testing"
+)
+
+// box is the exact signed distance of an axis aligned box.
+type box struct {
+	lo, hi Vec3
+}
+
+func (b box) Distance(p Vec3) float32 {
+	c := b.lo.Plus(b.hi).Mul(.5)
+	h := b.hi.Minus(b.lo).Mul(.5)
+	d := p.Minus(c)
+	q := Vec3 { abs(d.X) - h.X, abs(d.Y) - h.Y, abs(d.Z) - h.Z }
+	out := q.Clamp(Vec3 {}, Vec3 { 1e9, 1e9, 1e9 }).Norm()
+	in := q.X; if q.Y > in { in = q.Y }; if q.Z > in { in = q.Z }
+	if in > 0 { in = 0 }
+	return out + in
+}
+
+func (b box) Inside(p Vec3) bool { return b.Distance(p) < 0 }
+
+func (b box) Intersect(a, c Vec3) (p, n Vec3) {
+	p = bisect(b.Inside, a, c)
+	n = gradient(b.Distance, p, 1e-3)
+	return
+}
+
+func hasVertex(m *Mesh, p Vec3, eps float32) bool {
+	for _, v := range m.Vertices {
+		if v.Minus(p).Norm() < eps { return true }
+	}
+	return false
+}
+
+func TestDualContour(t *testing.T) {
+
+	b := box { Vec3 { 2.3, 2.3, 2.3 }, Vec3 { 9.6, 7.4, 8.2 } }
+
+	m := DualContour(b, Vec3 {}, Size { 12, 12, 12 }, 1, 0)
+	vol := checkClosed(t, m)
+	if exp := 7.3 * 5.1 * 5.9; vol < exp - .5 || vol > exp + .5 {
+		t.Errorf("volume %f expected, was %f", exp, vol)
+	}
+	if !hasVertex(m, b.hi, 1e-3) || !hasVertex(m, b.lo, 1e-3) {
+		t.Errorf("sharp box corners expected")
+	}
+
+	s := DualContour(b, Vec3 {}, Size { 12, 12, 12 }, 1, .01)
+	if len(s.Vertices) >= len(m.Vertices) {
+		t.Errorf("fewer vertices expected after merging, %d >= %d", len(s.Vertices), len(m.Vertices))
+	}
+	if !hasVertex(s, b.hi, 1e-3) {
+		t.Errorf("sharp corner expected after merging")
+	}
+}
+
+func TestDualContourMesh(t *testing.T) {
+
+	m := DualContour(MeshHermite(cubeTris(Vec3 { 2.5, 2.5, 2.5 }, 4)), Vec3 {}, Size { 10, 10, 10 }, 1, 0)
+	if vol := checkClosed(t, m); vol < 63.5 || vol > 64.5 {
+		t.Errorf("volume 64 expected, was %f", vol)
+	}
+	if corner := (Vec3 { 6.5, 6.5, 6.5 }); !hasVertex(m, corner, 1e-3) {
+		t.Errorf("sharp corner expected at %v", corner)
+	}
+}
+
+func TestDualContourField(t *testing.T) {
+
+	g := GridVolume { NewGrid(16, 16, 16) }
+	for z := 4; z < 12; z++ {
+		for y := 4; y < 12; y++ {
+			for x := 4; x < 12; x++ {
+				g.Set(x, y, z, 1)
+			}
+		}
+	}
+
+	m := DualContour(FieldHermite { DistanceField(g) }, Vec3 {}, Size { 16, 16, 16 }, 1, 0)
+	if vol := checkClosed(t, m); vol < 400 || vol > 600 {
+		t.Errorf("volume close to 512 expected, was %f", vol)
+	}
+}
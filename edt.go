@@ -0,0 +1,117 @@
+package glvox
+
+import (
+	"math"
+)
+
+// DistanceField computes a signed distance for every voxel of vol, in
+// voxels. With d the exact euclidean distance between the voxel's center
+// and the nearest center of the other kind, occupied (non-zero) or empty,
+// an empty voxel gets d - 0.5 and an occupied one 0.5 - d, so neighbours
+// of the surface are ±0.5. Off the axes this overestimates the distance
+// to the blocky surface. Space outside vol counts as empty. A volume
+// without any occupied voxel is +Inf everywhere.
+func DistanceField(vol SizedGetter) *FloatGrid {
+
+	s := vol.Size()
+	m := loadMask(vol, 0, 0, 0, s.W, s.H, s.D)
+
+	// one voxel of empty border lets the outside act as surface
+	pm := m.grow(1)
+	ps := Size { pm.w, pm.h, pm.d }
+
+	outside := sqrEDT(pm, ps, true)
+	inside := sqrEDT(pm, ps, false)
+
+	g := NewFloatGrid(int32(s.W), int32(s.H), int32(s.D))
+	for z := 0; z < s.D; z++ {
+		for y := 0; y < s.H; y++ {
+			for x := 0; x < s.W; x++ {
+				i := ((z+1)*ps.H + y+1)*ps.W + x+1
+				j := (z*s.H + y)*s.W + x
+				if m.get(x, y, z) {
+					g.data[j] = -float32(math.Sqrt(inside[i]) - .5)
+				} else {
+					g.data[j] = float32(math.Sqrt(outside[i]) - .5)
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// sqrEDT returns the squared distance of every voxel to the nearest voxel
+// whose occupancy equals target, by the separable algorithm of
+// Felzenszwalb and Huttenlocher.
+func sqrEDT(m *mask, s Size, target bool) []float64 {
+
+	f := make([]float64, s.W*s.H*s.D)
+	for z := 0; z < s.D; z++ {
+		for y := 0; y < s.H; y++ {
+			for x := 0; x < s.W; x++ {
+				if m.get(x, y, z) != target { f[(z*s.H + y)*s.W + x] = math.Inf(1) }
+			}
+		}
+	}
+
+	n := s.W; if s.H > n { n = s.H }; if s.D > n { n = s.D }
+	line, out := make([]float64, n), make([]float64, n)
+	v, b := make([]int, n), make([]float64, n+1)
+
+	pass := func(start, step, cnt int) {
+		for i := 0; i < cnt; i++ { line[i] = f[start + i*step] }
+		edt1(line[:cnt], out[:cnt], v, b)
+		for i := 0; i < cnt; i++ { f[start + i*step] = out[i] }
+	}
+
+	for z := 0; z < s.D; z++ {
+		for y := 0; y < s.H; y++ { pass((z*s.H + y)*s.W, 1, s.W) }
+	}
+	for z := 0; z < s.D; z++ {
+		for x := 0; x < s.W; x++ { pass(z*s.H*s.W + x, s.W, s.H) }
+	}
+	for y := 0; y < s.H; y++ {
+		for x := 0; x < s.W; x++ { pass(y*s.W + x, s.H*s.W, s.D) }
+	}
+
+	return f
+}
+
+// edt1 is the one dimensional squared distance transform of f: the lower
+// envelope of the parabolas rooted at every finite sample.
+func edt1(f, d []float64, v []int, b []float64) {
+
+	k := -1
+	for q := range f {
+		if math.IsInf(f[q], 1) { continue }
+
+		for k >= 0 {
+			p := v[k]
+			s := ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*(q - p))
+			if s > b[k] { break }
+			k--
+		}
+
+		k++
+		v[k] = q
+		if k == 0 {
+			b[k] = math.Inf(-1)
+		} else {
+			p := v[k-1]
+			b[k] = ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*(q - p))
+		}
+	}
+
+	if k < 0 {
+		for q := range d { d[q] = math.Inf(1) }
+		return
+	}
+
+	j := 0
+	for q := range d {
+		for j < k && b[j+1] < float64(q) { j++ }
+		dq := float64(q - v[j])
+		d[q] = dq*dq + f[v[j]]
+	}
+}
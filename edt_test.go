@@ -0,0 +1,47 @@
+package glvox
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceField(t *testing.T) {
+
+	vol := GridVolume { NewGrid(9, 9, 9) }
+	for z := 3; z < 6; z++ {
+		for y := 3; y < 6; y++ {
+			for x := 3; x < 6; x++ {
+				vol.Set(x, y, z, 1)
+			}
+		}
+	}
+
+	sdf := DistanceField(vol)
+
+	tests := []struct {
+		x, y, z int32
+		d float64
+	}{
+		{ 4, 4, 4, -1.5 },
+		{ 3, 4, 4, -.5 },
+		{ 2, 4, 4, .5 },
+		{ 0, 4, 4, 2.5 },
+		{ 2, 2, 4, math.Sqrt(2) - .5 },
+		{ 0, 0, 0, math.Sqrt(27) - .5 },
+	}
+
+	for _, tt := range tests {
+		d := float64(sdf.Get(tt.x, tt.y, tt.z))
+		if math.Abs(d - tt.d) > 1e-5 {
+			t.Errorf("(%d, %d, %d): %f expected, was %f", tt.x, tt.y, tt.z, tt.d, d)
+		}
+	}
+
+	// the border of the volume counts as outside
+	full := GridVolume { NewGrid(5, 1, 1) }
+	for x := 0; x < 5; x++ { full.Set(x, 0, 0, 1) }
+	sdf = DistanceField(full)
+	if d := sdf.Get(2, 0, 0); d != -.5 {
+		t.Errorf("-0.5 expected at the center of a filled row, was %f", d)
+	}
+}
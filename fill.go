@@ -0,0 +1,128 @@
+package glvox
+
+// Connectivity selects which neighbours of a voxel count as connected.
+type Connectivity int
+
+const (
+	Conn6 Connectivity = 6		// shared faces
+	Conn18 Connectivity = 18	// shared faces or edges
+	Conn26 Connectivity = 26	// shared faces, edges or corners
+)
+
+// FloodFill sets every voxel connected to (x, y, z) whose value satisfies
+// match to v and returns the number of voxels changed. A nil match selects
+// voxels equal to the seed value. The fill works on whole octree leaves
+// with an explicit stack, so large regions cost no more than small ones.
+func FloodFill(oct *Octree, x, y, z int, v int,
+	match func(val int) bool, conn Connectivity) (n int) {
+
+	var seed []leaf
+	oct.leaves(x, y, z, x+1, y+1, z+1, func(l leaf) {
+		seed = append(seed, l)
+	})
+	if len(seed) == 0 { return }
+
+	if match == nil {
+		sv := seed[0].val
+		match = func(val int) bool { return val == sv }
+	}
+
+	region := oct.flood(seed, match, conn)
+	for _, l := range region {
+		if l.val == v { continue }
+		oct.setNode(l.x, l.y, l.z, l.size, v)
+		n += l.size * l.size * l.size
+	}
+
+	return
+}
+
+// FillInterior sets every empty voxel that cannot be reached from outside
+// the octree to v and returns the number of voxels changed. The exterior
+// is flooded with the given connectivity; Conn6 is the most conservative
+// choice and seals shells that are only diagonally closed.
+func FillInterior(oct *Octree, v int, conn Connectivity) (n int) {
+
+	s := oct.Size
+	var seed []leaf
+	border := func(l leaf) {
+		if l.val == 0 { seed = append(seed, l) }
+	}
+	oct.leaves(0, 0, 0, 1, s, s, border)
+	oct.leaves(s-1, 0, 0, s, s, s, border)
+	oct.leaves(0, 0, 0, s, 1, s, border)
+	oct.leaves(0, s-1, 0, s, s, s, border)
+	oct.leaves(0, 0, 0, s, s, 1, border)
+	oct.leaves(0, 0, s-1, s, s, s, border)
+
+	empty := func(val int) bool { return val == 0 }
+	outside := make(map[[3]int]bool)
+	for _, l := range oct.flood(seed, empty, conn) {
+		outside[[3]int{ l.x, l.y, l.z }] = true
+	}
+
+	var inside []leaf
+	oct.leaves(0, 0, 0, s, s, s, func(l leaf) {
+		if l.val == 0 && !outside[[3]int{ l.x, l.y, l.z }] {
+			inside = append(inside, l)
+		}
+	})
+
+	for _, l := range inside {
+		oct.setNode(l.x, l.y, l.z, l.size, v)
+		n += l.size * l.size * l.size
+	}
+
+	return
+}
+
+// flood returns all leaves reachable from seed through leaves satisfying match.
+func (oct *Octree) flood(seed []leaf, match func(val int) bool,
+	conn Connectivity) (region []leaf) {
+
+	visited := make(map[[3]int]bool)
+	var stack []leaf
+	for _, l := range seed {
+		key := [3]int{ l.x, l.y, l.z }
+		if visited[key] || !match(l.val) { continue }
+		visited[key] = true
+		stack = append(stack, l)
+	}
+
+	for len(stack) > 0 {
+		l := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		region = append(region, l)
+
+		oct.leaves(l.x-1, l.y-1, l.z-1,
+			l.x+l.size+1, l.y+l.size+1, l.z+l.size+1, func(c leaf) {
+
+			key := [3]int{ c.x, c.y, c.z }
+			if visited[key] || !match(c.val) || !touches(l, c, conn) { return }
+			visited[key] = true
+			stack = append(stack, c)
+		})
+	}
+
+	return
+}
+
+// touches reports whether two disjoint leaves are neighbours under conn.
+func touches(a, b leaf, conn Connectivity) bool {
+
+	contact := 0
+	axis := func(a0, b0 int) bool {
+		a1, b1 := a0 + a.size, b0 + b.size
+		if a0 < b1 && b0 < a1 { return true }
+		if a1 == b0 || b1 == a0 { contact++; return true }
+		return false
+	}
+
+	if !axis(a.x, b.x) || !axis(a.y, b.y) || !axis(a.z, b.z) { return false }
+
+	switch conn {
+	case Conn6: return contact == 1
+	case Conn18: return contact >= 1 && contact <= 2
+	}
+	return contact >= 1
+}
@@ -0,0 +1,76 @@
+package glvox
+
+import (
+	"testing"
+)
+
+// buildShell returns a 16³ octree holding the hollow shell of the cube [4,12)³.
+func buildShell() *Octree {
+
+	oct := NewOctree(16)
+	for z := 4; z < 12; z++ {
+		for y := 4; y < 12; y++ {
+			for x := 4; x < 12; x++ {
+				if x == 4 || x == 11 || y == 4 || y == 11 || z == 4 || z == 11 {
+					oct.Set(x, y, z, 1)
+				}
+			}
+		}
+	}
+
+	return oct
+}
+
+func TestFillInterior(t *testing.T) {
+
+	oct := buildShell()
+
+	n := FillInterior(oct, 2, Conn6)
+	if n != 6*6*6 {
+		t.Errorf("216 voxels filled expected, was %d", n)
+	}
+
+	for z := 0; z < 16; z++ {
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				exp := 0
+				if x >= 4 && x < 12 && y >= 4 && y < 12 && z >= 4 && z < 12 { exp = 1 }
+				if x >= 5 && x < 11 && y >= 5 && y < 11 && z >= 5 && z < 11 { exp = 2 }
+				if v, _ := oct.Get(x, y, z); v != exp {
+					t.Fatalf("(%d, %d, %d): expected %d, was %d", x, y, z, exp, v)
+				}
+			}
+		}
+	}
+}
+
+func TestFloodFill(t *testing.T) {
+
+	oct := buildShell()
+
+	n := FloodFill(oct, 7, 7, 7, 3, nil, Conn6)
+	if n != 6*6*6 {
+		t.Errorf("216 voxels filled expected, was %d", n)
+	}
+	if v, _ := oct.Get(0, 0, 0); v != 0 {
+		t.Errorf("outside untouched expected, was %d", v)
+	}
+
+	// a hole in an edge of the shell, only diagonally open to the interior
+	oct = buildShell()
+	oct.Set(4, 4, 7, 0)
+
+	n = FloodFill(oct, 7, 7, 7, 3, func(v int) bool { return v == 0 }, Conn6)
+	if n != 6*6*6 {
+		t.Errorf("conn6: 216 voxels filled expected, was %d", n)
+	}
+
+	leak := func(v int) bool { return v == 0 || v == 3 }
+	n = FloodFill(oct, 7, 7, 7, 4, leak, Conn18)
+	if exp := 16*16*16 - 8*8*8 + 1 + 6*6*6; n != exp {
+		t.Errorf("conn18: %d voxels filled expected, was %d", exp, n)
+	}
+	if v, _ := oct.Get(0, 0, 0); v != 4 {
+		t.Errorf("conn18: fill must leak outside, was %d", v)
+	}
+}
@@ -0,0 +1,247 @@
+package glvox
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrFormat is returned for data of no registered format, or for saving
+// in a format without encoder.
+var ErrFormat = errors.New("glvox: unknown format")
+
+// A format holds what RegisterFormat was given. Built in formats whose
+// prefix is not fixed have a probe looking at the data instead of magic.
+type format struct {
+	name, magic string
+	probe func(r *bufio.Reader) bool
+	decode func(io.Reader, Setter) error
+	config func(io.Reader) (Size, error)
+	encode func(io.Writer, SizedGetter) error
+}
+
+var (
+	formatsMu sync.Mutex
+	atomicFormats atomic.Value
+)
+
+// RegisterFormat registers a voxel format for Load, LoadConfig and Save,
+// in the spirit of image.RegisterFormat. Magic is the prefix identifying
+// the format, each "?" matching any byte. Decode sets the voxels of a
+// stream into dst with the lowest corner at the origin, config returns
+// the size of the volume and encode writes vol; config and encode may be
+// nil.
+func RegisterFormat(name, magic string, decode func(io.Reader, Setter) error,
+	config func(io.Reader) (Size, error), encode func(io.Writer, SizedGetter) error) {
+
+	register(format { name, magic, nil, decode, config, encode })
+}
+
+func register(f format) {
+	formatsMu.Lock()
+	formats, _ := atomicFormats.Load().([]format)
+	atomicFormats.Store(append(formats[:len(formats):len(formats)], f))
+	formatsMu.Unlock()
+}
+
+func match(magic string, b []byte) bool {
+	if len(magic) != len(b) { return false }
+	for i, c := range b {
+		if magic[i] != c && magic[i] != '?' { return false }
+	}
+	return true
+}
+
+// sniff returns the format of the data r starts with.
+func sniff(r *bufio.Reader) (f format, ok bool) {
+	formats, _ := atomicFormats.Load().([]format)
+	for _, f := range formats {
+		if f.probe != nil {
+			if f.probe(r) { return f, true }
+			continue
+		}
+		b, err := r.Peek(len(f.magic))
+		if err == nil && match(f.magic, b) { return f, true }
+	}
+	return
+}
+
+// isSchematic tells whether r starts with an NBT compound, plain or
+// gzipped, whose first tag is valid. Other gzip streams are left alone.
+func isSchematic(r *bufio.Reader) bool {
+	b, _ := r.Peek(1024)
+	if len(b) >= 2 && b[0] == 0x1f && b[1] == 0x8b {
+		z, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil { return false }
+		var head [259]byte
+		n, _ := io.ReadFull(z, head[:])
+		b = head[:n]
+	}
+
+	// TAG_Compound, the length of its name, the name and the first tag
+	if len(b) < 3 || b[0] != TagCompound { return false }
+	n := 3 + (int(b[1])<<8 | int(b[2]))
+	return len(b) > n && b[n] <= TagLongArray
+}
+
+// isPLY tells whether r starts with a PLY magic line, ended by LF or CRLF.
+func isPLY(r *bufio.Reader) bool {
+	b, _ := r.Peek(5)
+	s := string(b)
+	return strings.HasPrefix(s, "ply\n") || s == "ply\r\n"
+}
+
+// Load sets the voxels of r, in any registered format, into dst and
+// returns the name of the format.
+func Load(r io.Reader, dst Setter) (name string, err error) {
+	buf := bufio.NewReader(r)
+	f, ok := sniff(buf)
+	if !ok { return "", ErrFormat }
+	return f.name, f.decode(buf, dst)
+}
+
+// LoadConfig returns the size of the volume of r, in any registered
+// format, without setting its voxels, and the name of the format.
+func LoadConfig(r io.Reader) (size Size, name string, err error) {
+	buf := bufio.NewReader(r)
+	f, ok := sniff(buf)
+	if !ok || f.config == nil { return size, "", ErrFormat }
+	size, err = f.config(buf)
+	return size, f.name, err
+}
+
+// Save writes vol to w in the registered format name.
+func Save(w io.Writer, vol SizedGetter, name string) error {
+	formats, _ := atomicFormats.Load().([]format)
+	for _, f := range formats {
+		if f.name == name && f.encode != nil { return f.encode(w, vol) }
+	}
+	return ErrFormat
+}
+
+// discard is a Setter dropping all voxels.
+type discard struct{}
+
+func (discard) Set(x, y, z int, v int) {}
+
+func init() {
+	RegisterFormat("binvox", "#binvox",
+		func(r io.Reader, dst Setter) error {
+			_, err := DecodeBinvox(r, dst, 0, 0, 0)
+			return err
+		},
+		func(r io.Reader) (Size, error) {
+			dec, err := NewBinvoxDecoder(r)
+			if err != nil { return Size {}, err }
+			return dec.Header.Size, nil
+		},
+		func(w io.Writer, vol SizedGetter) error {
+			return WriteBinvox(w, vol, Vec3 {}, 1)
+		})
+
+	RegisterFormat("vox", "VOX ",
+		func(r io.Reader, dst Setter) error {
+			scene, err := ReadVox(r)
+			if err == nil { scene.Load(dst, 0, 0, 0) }
+			return err
+		},
+		func(r io.Reader) (Size, error) {
+			scene, err := ReadVox(r)
+			if err != nil { return Size {}, err }
+			return scene.Size(), nil
+		},
+		func(w io.Writer, vol SizedGetter) error {
+			return WriteVox(w, vol, nil)
+		})
+
+	// matrices are placed relative to the lowest one, which needs two passes
+	qb := func(r io.Reader, dst Setter) (size Size, err error) {
+		data, err := ioutil.ReadAll(r)
+		if err != nil { return }
+		ms, err := ReadQB(bytes.NewReader(data), discard {}, nil, 0, 0, 0)
+		if err != nil { return }
+
+		lo, hi := qbBounds(ms)
+		if dst != nil {
+			_, err = ReadQB(bytes.NewReader(data), dst, nil, -lo[0], -lo[1], -lo[2])
+		}
+		return Size { hi[0] - lo[0], hi[1] - lo[1], hi[2] - lo[2] }, err
+	}
+	RegisterFormat("qb", "\x01\x01\x00\x00",
+		func(r io.Reader, dst Setter) error {
+			_, err := qb(r, dst)
+			return err
+		},
+		func(r io.Reader) (Size, error) { return qb(r, nil) },
+		nil)
+
+	// samples of at least 0.5 become voxels of 1, which suits 0/1 and
+	// 0/255 masks; use ReadNRRD with Threshold or Quantize for others
+	nrrd := func(r io.Reader, dst Setter) (Size, error) {
+		g, err := ReadNRRD(r)
+		if err != nil { return Size {}, err }
+		if dst != nil { Threshold(g, dst, 0.5, 1) }
+		return Size { int(g.W), int(g.H), int(g.D) }, nil
+	}
+	RegisterFormat("nrrd", "NRRD000",
+		func(r io.Reader, dst Setter) error {
+			_, err := nrrd(r, dst)
+			return err
+		},
+		func(r io.Reader) (Size, error) { return nrrd(r, nil) },
+		nil)
+
+	// every block but air becomes a voxel of 1; use LoadSchematic with a
+	// BlockTable for block values
+	schematic := func(r io.Reader, dst Setter) (Size, error) {
+		if dst == nil { dst = discard {} }
+		return LoadSchematic(r, dst, &BlockTable { Default: 1 }, 0, 0, 0)
+	}
+	register(format { name: "schematic", probe: isSchematic,
+		decode: func(r io.Reader, dst Setter) error {
+			_, err := schematic(r, dst)
+			return err
+		},
+		config: func(r io.Reader) (Size, error) { return schematic(r, nil) },
+	})
+
+	// points are binned into voxels of 1 unit from the lowest point on,
+	// PackRGB of their average colour, or 1 without colours; use ReadPLY
+	// with VoxelizePoints for other scales
+	ply := func(r io.Reader, dst Setter) (size Size, err error) {
+		pc, err := ReadPLY(r)
+		if err != nil || len(pc.Points) == 0 { return }
+
+		lo := pc.Points[0]
+		for _, p := range pc.Points {
+			lo = Vec3 { min32(lo.X, p.X), min32(lo.Y, p.Y), min32(lo.Z, p.Z) }
+		}
+		floor := func(f float32) float32 { return float32(math.Floor(float64(f))) }
+		lo = Vec3 { floor(lo.X), floor(lo.Y), floor(lo.Z) }
+
+		value := func(b *PointBin) int { return 1 }
+		if pc.Colors != nil { value = func(b *PointBin) int { return PackRGB(b.Color) } }
+		if dst == nil { dst = discard {} }
+
+		for k := range VoxelizePoints(pc, dst, lo, 1, 1, value) {
+			if k[0] >= size.W { size.W = k[0]+1 }
+			if k[1] >= size.H { size.H = k[1]+1 }
+			if k[2] >= size.D { size.D = k[2]+1 }
+		}
+		return
+	}
+	register(format { name: "ply", probe: isPLY,
+		decode: func(r io.Reader, dst Setter) error {
+			_, err := ply(r, dst)
+			return err
+		},
+		config: func(r io.Reader) (Size, error) { return ply(r, nil) },
+	})
+}
@@ -0,0 +1,117 @@
+package glvox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+
+	// the test format below must not outlive the test
+	saved := atomicFormats.Load()
+	defer atomicFormats.Store(saved)
+
+	formats, _ := atomicFormats.Load().([]format)
+	if len(formats) == 0 || formats[0].name != "binvox" {
+		t.Fatal("binvox as first format expected")
+	}
+
+	src := GridVolume { NewGrid(3, 4, 5) }
+	src.Set(0, 0, 0, 1)
+	src.Set(2, 3, 4, 9)
+
+	for _, name := range []string { "binvox", "vox" } {
+		var buf bytes.Buffer
+		if err := Save(&buf, src, name); err != nil { t.Fatal(err) }
+
+		size, got, err := LoadConfig(bytes.NewReader(buf.Bytes()))
+		if err != nil || got != name || size != (Size { 3, 4, 5 }) {
+			t.Errorf("%s: unexpected config %v %q %v", name, size, got, err)
+		}
+
+		dst := NewOctree(8)
+		if got, err = Load(&buf, dst); err != nil || got != name {
+			t.Fatalf("%s: unexpected format %q %v", name, got, err)
+		}
+		if v, _ := dst.Get(2, 3, 4); v != 9 {
+			t.Errorf("%s: 9 at (2, 3, 4) expected, was %d", name, v)
+		}
+	}
+
+	// qb matrices at negative positions are moved to the origin
+	f := qbFile([5]uint32 { 0, 0, 1, 0, 0 },
+		[]uint32 { 1, 1, 1, 0xfffffffe, 0, 0,  0xff0000ff },
+		[]uint32 { 1, 1, 1, 2, 0, 0,  0xff00ff00 })
+	size, name, err := LoadConfig(bytes.NewReader(f.Bytes()))
+	if err != nil || name != "qb" || size != (Size { 5, 1, 1 }) {
+		t.Errorf("qb: unexpected config %v %q %v", size, name, err)
+	}
+	dst := NewOctree(8)
+	if _, err := Load(f, dst); err != nil { t.Fatal(err) }
+	if v, _ := dst.Get(4, 0, 0); v != PackRGB([3]uint8 { 0, 0xff, 0 }) {
+		t.Errorf("qb: green at (4, 0, 0) expected, was %x", v)
+	}
+	if Save(io.Discard, src, "qb") != ErrFormat {
+		t.Error("qb: no encoder expected")
+	}
+
+	// formats without encoder load with their documented defaults
+	blocks := make([]byte, 12)
+	blocks[11] = 35
+	loads := []struct {
+		name string
+		in *bytes.Buffer
+		size Size
+		x, y, z, v int
+	}{
+		{ "nrrd", bytes.NewBufferString("NRRD0004\ntype: uchar\ndimension: 3\nsizes: 1 1 3\nencoding: raw\n\n\x00\x00\xff"),
+			Size { 1, 1, 3 }, 0, 0, 2, 1 },
+		{ "schematic", nbtFile("Schematic", map[string]interface{} {
+			"Width": int16(2), "Height": int16(2), "Length": int16(3), "Materials": "Alpha",
+			"Blocks": blocks, "Data": make([]byte, 12) }, true),
+			Size { 2, 2, 3 }, 1, 1, 2, 1 },
+		{ "schematic", nbtFile("Schematic", map[string]interface{} {
+			"Width": int16(2), "Height": int16(2), "Length": int16(3), "Materials": "Alpha",
+			"Blocks": blocks, "Data": make([]byte, 12) }, false),
+			Size { 2, 2, 3 }, 1, 1, 2, 1 },
+		{ "ply", bytes.NewBufferString("ply\nformat ascii 1.0\nelement vertex 2\nproperty float x\nproperty float y\nproperty float z\nend_header\n" +
+			"-1.5 0 0\n1.2 2 0.5\n"),
+			Size { 4, 3, 1 }, 3, 2, 0, 1 },
+		{ "ply", bytes.NewBufferString("ply\r\nformat ascii 1.0\r\nelement vertex 1\r\nproperty float x\r\nproperty float y\r\nproperty float z\r\nend_header\r\n" +
+			"0 0 0\r\n"),
+			Size { 1, 1, 1 }, 0, 0, 0, 1 },
+	}
+	for _, l := range loads {
+		size, name, err := LoadConfig(bytes.NewReader(l.in.Bytes()))
+		if err != nil || name != l.name || size != l.size {
+			t.Errorf("%s: unexpected config %v %q %v", l.name, size, name, err)
+		}
+		dst := NewOctree(8)
+		if _, err := Load(l.in, dst); err != nil { t.Fatalf("%s: %v", l.name, err) }
+		if v, _ := dst.Get(l.x, l.y, l.z); v != l.v {
+			t.Errorf("%s: %d at (%d, %d, %d) expected, was %d", l.name, l.v, l.x, l.y, l.z, v)
+		}
+	}
+
+	// other gzip streams are no schematics
+	var gz bytes.Buffer
+	z := gzip.NewWriter(&gz)
+	WriteBinvox(z, src, Vec3 {}, 1)
+	z.Close()
+	if _, name, err := LoadConfig(&gz); err != ErrFormat {
+		t.Errorf("gzipped binvox: ErrFormat expected, was %q %v", name, err)
+	}
+
+	RegisterFormat("test", "T?ST", func(r io.Reader, dst Setter) error {
+		dst.Set(1, 1, 1, 7)
+		return nil
+	}, nil, nil)
+	if name, err := Load(bytes.NewBufferString("TEST"), dst); err != nil || name != "test" {
+		t.Errorf("test format expected, was %q %v", name, err)
+	}
+	if _, err := Load(bytes.NewBufferString("BEST"), dst); err != ErrFormat {
+		t.Errorf("ErrFormat expected, was %v", err)
+	}
+}
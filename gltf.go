@@ -0,0 +1,209 @@
+package glvox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+)
+
+// GLTFOptions control how WriteGLTF and WriteGLB write a mesh. Colour
+// maps the voxel values of the mesh to colours, white if nil. They
+// become vertex colours, or with Materials set one material per value.
+// If URI is set WriteGLTF writes the binary buffer to Bin and refers to
+// it by URI instead of embedding it.
+type GLTFOptions struct {
+	Colour func(v int) color.Color
+	Materials bool
+	URI string
+	Bin io.Writer
+}
+
+const (
+	gltfFloat = 5126
+	gltfUint = 5125
+	gltfArrayBuffer = 34962
+	gltfElementBuffer = 34963
+)
+
+// WriteGLTF writes m as a glTF 2.0 scene with positions, normals and,
+// if m has values, colours.
+func WriteGLTF(w io.Writer, m *Mesh, opt *GLTFOptions) error {
+
+	if opt == nil { opt = &GLTFOptions {} }
+	doc, bin := gltfDocument(m, opt)
+
+	if buffers, ok := doc["buffers"].([]map[string]interface{}); ok {
+		if opt.URI != "" {
+			if opt.Bin == nil { return errors.New("gltf: URI without Bin") }
+			if _, err := opt.Bin.Write(bin); err != nil { return err }
+			buffers[0]["uri"] = opt.URI
+		} else {
+			buffers[0]["uri"] = "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// WriteGLB writes m like WriteGLTF as a single binary .glb file.
+func WriteGLB(w io.Writer, m *Mesh, opt *GLTFOptions) error {
+
+	if opt == nil { opt = &GLTFOptions {} }
+	doc, bin := gltfDocument(m, opt)
+
+	js, err := json.Marshal(doc)
+	if err != nil { return err }
+	for len(js) % 4 != 0 { js = append(js, ' ') }
+
+	le := binary.LittleEndian
+	var out bytes.Buffer
+	out.WriteString("glTF")
+	size := 12 + 8 + len(js)
+	if len(bin) > 0 { size += 8 + len(bin) }
+	binary.Write(&out, le, [2]uint32 { 2, uint32(size) })
+	binary.Write(&out, le, [2]uint32 { uint32(len(js)), 0x4e4f534a })
+	out.Write(js)
+	if len(bin) > 0 {
+		binary.Write(&out, le, [2]uint32 { uint32(len(bin)), 0x004e4942 })
+		out.Write(bin)
+	}
+
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+// linear converts an sRGB colour to the linear RGBA glTF expects.
+func linear(c color.Color) [4]float32 {
+	r, g, b, a := color.NRGBAModel.Convert(c).RGBA()
+	f := func(v uint32) float32 {
+		s := float64(v) / 0xffff
+		if s <= 0.04045 { return float32(s / 12.92) }
+		return float32(math.Pow((s + 0.055) / 1.055, 2.4))
+	}
+	return [4]float32 { f(r), f(g), f(b), float32(a) / 0xffff }
+}
+
+// gltfDocument builds the JSON document of m, without the URI of its
+// buffer, and the buffer. Vertex attributes and indices each get a
+// buffer view; every primitive an accessor into the indices.
+func gltfDocument(m *Mesh, opt *GLTFOptions) (doc map[string]interface{}, bin []byte) {
+
+	type obj = map[string]interface{}
+
+	colour := func(v int) [4]float32 {
+		if opt.Colour == nil { return [4]float32 { 1, 1, 1, 1 } }
+		return linear(opt.Colour(v))
+	}
+
+	var buf bytes.Buffer
+	var views, accessors []obj
+	le := binary.LittleEndian
+
+	view := func(data interface{}, target int) int {
+		for buf.Len() % 4 != 0 { buf.WriteByte(0) }
+		off := buf.Len()
+		binary.Write(&buf, le, data)
+		views = append(views, obj { "buffer": 0, "byteOffset": off, "byteLength": buf.Len() - off, "target": target })
+		return len(views) - 1
+	}
+	accessor := func(a obj) int {
+		accessors = append(accessors, a)
+		return len(accessors) - 1
+	}
+
+	doc = obj {
+		"asset": obj { "version": "2.0", "generator": "glvox" },
+		"scene": 0,
+		"scenes": []obj { { "nodes": []int { 0 } } },
+		"nodes": []obj { {} },
+	}
+
+	if len(m.Indices) > 0 {
+		lo, hi := m.Vertices[0], m.Vertices[0]
+		for _, p := range m.Vertices {
+			lo = Vec3 { min32(lo.X, p.X), min32(lo.Y, p.Y), min32(lo.Z, p.Z) }
+			hi = Vec3 { max32(hi.X, p.X), max32(hi.Y, p.Y), max32(hi.Z, p.Z) }
+		}
+
+		attr := obj {}
+		attr["POSITION"] = accessor(obj { "bufferView": view(m.Vertices, gltfArrayBuffer),
+			"componentType": gltfFloat, "count": len(m.Vertices), "type": "VEC3",
+			"min": []float32 { lo.X, lo.Y, lo.Z }, "max": []float32 { hi.X, hi.Y, hi.Z } })
+
+		if len(m.Normals) == len(m.Vertices) {
+			// normals of degenerate faces may be NaN, which viewers reject
+			normals := make([]Vec3, len(m.Normals))
+			for i, n := range m.Normals {
+				if n.X == n.X && n.Y == n.Y && n.Z == n.Z { normals[i] = n }
+			}
+			attr["NORMAL"] = accessor(obj { "bufferView": view(normals, gltfArrayBuffer),
+				"componentType": gltfFloat, "count": len(normals), "type": "VEC3" })
+		}
+
+		valued := len(m.Values) == len(m.Vertices)
+		if valued && !opt.Materials {
+			colours := make([][4]float32, len(m.Values))
+			cache := make(map[int][4]float32)
+			for i, v := range m.Values {
+				c, ok := cache[v]
+				if !ok { c = colour(v); cache[v] = c }
+				colours[i] = c
+			}
+			attr["COLOR_0"] = accessor(obj { "bufferView": view(colours, gltfArrayBuffer),
+				"componentType": gltfFloat, "count": len(colours), "type": "VEC4" })
+		}
+
+		// triangles grouped by the value of their first vertex
+		var groups []int
+		byValue := map[int][]uint32 {}
+		for i := 0; i+2 < len(m.Indices); i += 3 {
+			v := 0
+			if valued && opt.Materials { v = m.Values[m.Indices[i]] }
+			if _, ok := byValue[v]; !ok { groups = append(groups, v) }
+			byValue[v] = append(byValue[v], uint32(m.Indices[i]), uint32(m.Indices[i+1]), uint32(m.Indices[i+2]))
+		}
+		sort.Ints(groups)
+
+		var indices []uint32
+		var prims, materials []obj
+		for _, v := range groups {
+			p := obj { "attributes": attr, "mode": 4 }
+			if valued && opt.Materials {
+				p["material"] = len(materials)
+				materials = append(materials, obj { "pbrMetallicRoughness": obj {
+					"baseColorFactor": colour(v), "metallicFactor": 0 } })
+			}
+			p["indices"] = len(accessors) + len(prims)
+			prims = append(prims, p)
+			indices = append(indices, byValue[v]...)
+		}
+
+		iv := view(indices, gltfElementBuffer)
+		off := 0
+		for _, v := range groups {
+			accessor(obj { "bufferView": iv, "byteOffset": 4*off,
+				"componentType": gltfUint, "count": len(byValue[v]), "type": "SCALAR" })
+			off += len(byValue[v])
+		}
+
+		doc["nodes"] = []obj { { "mesh": 0 } }
+		doc["meshes"] = []obj { { "primitives": prims } }
+		if materials != nil { doc["materials"] = materials }
+		doc["accessors"] = accessors
+		doc["bufferViews"] = views
+	}
+
+	// an empty mesh has no buffer
+	for buf.Len() % 4 != 0 { buf.WriteByte(0) }
+	bin = buf.Bytes()
+	if len(bin) > 0 { doc["buffers"] = []obj { { "byteLength": len(bin) } } }
+	return
+}
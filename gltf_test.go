@@ -0,0 +1,147 @@
+package glvox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+type gltfDoc struct {
+	Buffers []struct { ByteLength int; URI string }
+	BufferViews []struct { ByteOffset, ByteLength int }
+	Accessors []struct { BufferView, ByteOffset, Count int; Type string; Min, Max []float32 }
+	Meshes []struct { Primitives []struct { Attributes map[string]int; Indices int; Material *int } }
+	Materials []struct { PbrMetallicRoughness struct { BaseColorFactor []float32 } }
+}
+
+func TestWriteGLTF(t *testing.T) {
+
+	oct := NewOctree(4)
+	oct.Set(0, 0, 0, 1)
+	oct.Set(1, 0, 0, 2)
+	m := GreedyMesh(OctreeVolume { oct })
+	m.smoothNormals()
+
+	red := func(v int) color.Color {
+		if v == 1 { return color.RGBA { 255, 0, 0, 255 } }
+		return color.RGBA { 128, 128, 128, 255 }
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGLTF(&buf, m, &GLTFOptions { Colour: red }); err != nil { t.Fatal(err) }
+
+	var doc gltfDoc
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil { t.Fatal(err) }
+	prims := doc.Meshes[0].Primitives
+	if len(prims) != 1 || prims[0].Attributes["COLOR_0"] == 0 || prims[0].Attributes["NORMAL"] == 0 {
+		t.Fatalf("one primitive with colours and normals expected, was %+v", prims)
+	}
+
+	uri := doc.Buffers[0].URI
+	if !strings.HasPrefix(uri, "data:application/octet-stream;base64,") {
+		t.Fatalf("embedded buffer expected, was %q", uri[:20])
+	}
+	bin, err := base64.StdEncoding.DecodeString(uri[strings.Index(uri, ",")+1:])
+	if err != nil || len(bin) != doc.Buffers[0].ByteLength { t.Fatal("bad buffer", err) }
+
+	pos := doc.Accessors[prims[0].Attributes["POSITION"]]
+	if pos.Count != len(m.Vertices) || pos.Min[0] != 0 || pos.Max[0] != 2 || pos.Max[1] != 1 {
+		t.Errorf("unexpected positions %+v", pos)
+	}
+
+	// colours are linear
+	col := doc.Accessors[prims[0].Attributes["COLOR_0"]]
+	v := doc.BufferViews[col.BufferView]
+	colours := make([][4]float32, col.Count)
+	binary.Read(bytes.NewReader(bin[v.ByteOffset:v.ByteOffset+v.ByteLength]), binary.LittleEndian, colours)
+	for i, c := range colours {
+		exp := [4]float32 { 1, 0, 0, 1 }
+		if m.Values[i] == 2 { exp = [4]float32 { .2158605, .2158605, .2158605, 1 } }
+		if abs(c[0] - exp[0]) > 1e-4 || c[1] != exp[1] || c[3] != 1 {
+			t.Fatalf("vertex %d: colour %v expected, was %v", i, exp, c)
+		}
+	}
+
+	// one material per value, the buffer in a separate file
+	var sep bytes.Buffer
+	buf.Reset()
+	if err := WriteGLTF(&buf, m, &GLTFOptions { Colour: red, Materials: true, URI: "box.bin", Bin: &sep }); err != nil {
+		t.Fatal(err)
+	}
+	doc = gltfDoc {}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil { t.Fatal(err) }
+	prims = doc.Meshes[0].Primitives
+	if len(prims) != 2 || len(doc.Materials) != 2 || prims[1].Material == nil || *prims[1].Material != 1 {
+		t.Fatalf("two primitives with materials expected, was %+v", prims)
+	}
+	if doc.Buffers[0].URI != "box.bin" || doc.Buffers[0].ByteLength != sep.Len() {
+		t.Errorf("separate buffer expected, was %+v of %d bytes", doc.Buffers[0], sep.Len())
+	}
+	if _, ok := prims[0].Attributes["COLOR_0"]; ok {
+		t.Error("no vertex colours expected with materials")
+	}
+	a, b := doc.Accessors[prims[0].Indices], doc.Accessors[prims[1].Indices]
+	if a.Count + b.Count != len(m.Indices) || b.ByteOffset != 4*a.Count {
+		t.Errorf("index ranges %+v %+v do not cover the mesh", a, b)
+	}
+	if f := doc.Materials[0].PbrMetallicRoughness.BaseColorFactor; len(f) != 4 || f[0] != 1 {
+		t.Errorf("red material expected, was %v", f)
+	}
+}
+
+func TestWriteGLB(t *testing.T) {
+
+	oct := NewOctree(4)
+	oct.Set(1, 1, 1, 1)
+	m := GreedyMesh(OctreeVolume { oct })
+
+	var buf bytes.Buffer
+	if err := WriteGLB(&buf, m, nil); err != nil { t.Fatal(err) }
+
+	b := buf.Bytes()
+	le := binary.LittleEndian
+	if string(b[:4]) != "glTF" || le.Uint32(b[4:]) != 2 || int(le.Uint32(b[8:])) != len(b) {
+		t.Fatalf("bad header % x", b[:12])
+	}
+
+	n := int(le.Uint32(b[12:]))
+	if n % 4 != 0 || le.Uint32(b[16:]) != 0x4e4f534a {
+		t.Fatal("bad JSON chunk")
+	}
+	var doc gltfDoc
+	if err := json.Unmarshal(b[20:20+n], &doc); err != nil { t.Fatal(err) }
+
+	bin := b[20+n:]
+	if int(le.Uint32(bin)) != doc.Buffers[0].ByteLength || le.Uint32(bin[4:]) != 0x004e4942 || len(bin) != 8 + doc.Buffers[0].ByteLength {
+		t.Error("bad BIN chunk")
+	}
+	if doc.Buffers[0].URI != "" {
+		t.Error("no URI expected in a glb")
+	}
+
+	// NaN normals of degenerate faces are written as zero
+	nan := float32(math.NaN())
+	flat := &Mesh { Vertices: make([]Vec3, 3), Normals: []Vec3 { { nan, nan, nan }, { 0, 1, 0 }, {} }, Indices: []int { 0, 1, 2 } }
+	buf.Reset()
+	if err := WriteGLB(&buf, flat, nil); err != nil { t.Fatal(err) }
+	b = buf.Bytes()
+	n = int(le.Uint32(b[12:]))
+	json.Unmarshal(b[20:20+n], &doc)
+	v := doc.BufferViews[doc.Accessors[doc.Meshes[0].Primitives[0].Attributes["NORMAL"]].BufferView]
+	normals := make([]Vec3, 3)
+	binary.Read(bytes.NewReader(b[28+n+v.ByteOffset:]), le, normals)
+	if normals[0] != (Vec3 {}) || normals[1] != (Vec3 { 0, 1, 0 }) {
+		t.Errorf("zero for a NaN normal expected, was %v", normals)
+	}
+
+	buf.Reset()
+	if err := WriteGLB(&buf, &Mesh {}, nil); err != nil { t.Fatal(err) }
+	if int(le.Uint32(buf.Bytes()[8:])) != buf.Len() || bytes.Contains(buf.Bytes(), []byte("buffers")) {
+		t.Error("an empty mesh without buffer expected")
+	}
+}
@@ -0,0 +1,107 @@
+package glvox
+
+// GreedyMesh builds the blocky surface of vol: one quad for every maximal
+// rectangle of exposed voxel faces sharing plane, direction and value. A
+// face is exposed where a non-zero voxel borders an empty one or the
+// outside of the volume. Every quad has its own four vertices carrying the
+// face normal and the voxel value.
+func GreedyMesh(vol SizedGetter) *Mesh {
+
+	mesh := new(Mesh)
+
+	x0, y0, z0, x1, y1, z1 := bounds(vol, 0)
+	if x0 >= x1 || y0 >= y1 || z0 >= z1 { return mesh }
+
+	g := loadGrid(vol, x0, y0, z0, x1, y1, z1)
+	org := [3]int { x0, y0, z0 }
+	dims := [3]int { int(g.W), int(g.H), int(g.D) }
+
+	get := func(p [3]int) int32 {
+		for k := range p {
+			if p[k] < 0 || p[k] >= dims[k] { return 0 }
+		}
+		return g.Get(int32(p[0]), int32(p[1]), int32(p[2]))
+	}
+
+	for a := 0; a < 3; a++ {
+		u, v := (a+1) % 3, (a+2) % 3
+		w, h := dims[u], dims[v]
+		faces := make([]int32, w*h)
+
+		for s := 0; s <= dims[a]; s++ {
+
+			// faces between the layers s-1 and s, negative when facing -a
+			var p [3]int
+			for j := 0; j < h; j++ {
+				for i := 0; i < w; i++ {
+					p[u], p[v] = i, j
+					p[a] = s-1; back := get(p)
+					p[a] = s; front := get(p)
+
+					f := int32(0)
+					if back != 0 && front == 0 { f = back }
+					if back == 0 && front != 0 { f = -front }
+					faces[j*w + i] = f
+				}
+			}
+
+			for j := 0; j < h; j++ {
+				for i := 0; i < w; {
+					f := faces[j*w + i]
+					if f == 0 { i++; continue }
+
+					fw := 1
+					for i+fw < w && faces[j*w + i+fw] == f { fw++ }
+
+					fh := 1
+					grow: for j+fh < h {
+						for k := 0; k < fw; k++ {
+							if faces[(j+fh)*w + i+k] != f { break grow }
+						}
+						fh++
+					}
+
+					for l := 0; l < fh; l++ {
+						for k := 0; k < fw; k++ { faces[(j+l)*w + i+k] = 0 }
+					}
+
+					mesh.quad(a, u, v, org, s, i, j, fw, fh, f)
+					i += fw
+				}
+			}
+		}
+	}
+
+	return mesh
+}
+
+// quad appends the rectangle [i,i+w)×[j,h+j) on plane s of axis a.
+// Negative f makes it face -a.
+func (m *Mesh) quad(a, u, v int, org [3]int, s, i, j, w, h int, f int32) {
+
+	corner := func(du, dv int) Vec3 {
+		var p [3]float32
+		p[a] = float32(org[a] + s)
+		p[u] = float32(org[u] + i + du)
+		p[v] = float32(org[v] + j + dv)
+		return Vec3 { p[0], p[1], p[2] }
+	}
+
+	var n [3]float32
+	n[a] = 1
+	val := int(f)
+	if f < 0 { n[a] = -1; val = -val }
+
+	base := len(m.Vertices)
+	m.Vertices = append(m.Vertices, corner(0, 0), corner(w, 0), corner(w, h), corner(0, h))
+	for k := 0; k < 4; k++ {
+		m.Normals = append(m.Normals, Vec3 { n[0], n[1], n[2] })
+		m.Values = append(m.Values, val)
+	}
+
+	if f > 0 {
+		m.Indices = append(m.Indices, base, base+1, base+2,  base, base+2, base+3)
+	} else {
+		m.Indices = append(m.Indices, base, base+2, base+1,  base, base+3, base+2)
+	}
+}
@@ -0,0 +1,55 @@
+package glvox
+
+import (
+	"testing"
+)
+
+func TestGreedyMesh(t *testing.T) {
+
+	oct := NewOctree(32)
+	for z := 4; z < 7; z++ {
+		for y := 4; y < 6; y++ {
+			for x := 4; x < 8; x++ {
+				oct.Set(x, y, z, 1)
+			}
+		}
+	}
+
+	m := GreedyMesh(OctreeVolume { oct })
+	if n := len(m.Indices) / 6; n != 6 {
+		t.Errorf("6 quads expected for a box, was %d", n)
+	}
+
+	area := float32(0)
+	for i := 0; i < len(m.Indices); i += 3 {
+		a, b, c := m.Vertices[m.Indices[i]], m.Vertices[m.Indices[i+1]], m.Vertices[m.Indices[i+2]]
+		n := b.Minus(a).Cross(c.Minus(a))
+		if n.Dot(m.Normals[m.Indices[i]]) <= 0 {
+			t.Errorf("triangle %v %v %v faces against its normal", a, b, c)
+		}
+		area += n.Norm() / 2
+	}
+	if area != 2*(4*2 + 4*3 + 2*3) {
+		t.Errorf("area 52 expected, was %f", area)
+	}
+
+	// a different value in a corner splits three faces in three quads
+	// each and adds no inner faces
+	oct.Set(7, 5, 6, 2)
+	m = GreedyMesh(OctreeVolume { oct })
+	if n := len(m.Indices) / 6; n != 12 {
+		t.Errorf("12 quads expected, was %d", n)
+	}
+	twos := 0
+	for _, v := range m.Values { if v == 2 { twos++ } }
+	if twos != 3*4 {
+		t.Errorf("3 quads of value 2 expected, was %d", twos/4)
+	}
+
+	// faces on the volume border are closed
+	g := GridVolume { NewGrid(2, 2, 2) }
+	g.Set(0, 0, 0, 3)
+	if n := len(GreedyMesh(g).Indices) / 6; n != 6 {
+		t.Errorf("6 quads expected at the border, was %d", n)
+	}
+}
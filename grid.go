@@ -85,3 +85,27 @@ func (g *Grid) String() string {
 
 	return s
 }
+
+type FloatGrid struct {
+	data []float32
+	W, H, D int32
+}
+
+func NewFloatGrid(w, h, d int32) *FloatGrid {
+	g := new(FloatGrid)
+	g.data = make([]float32, w*h*d)
+	g.W = w; g.H = h; g.D = d
+	return g
+}
+
+func (g *FloatGrid) Set(x, y, z int32, val float32) {
+	g.data[z*g.H*g.W + y*g.W + x] = val
+}
+
+func (g *FloatGrid) Get(x, y, z int32) float32 {
+	return g.data[z*g.H*g.W + y*g.W + x]
+}
+
+func (g *FloatGrid) Size() Size {
+	return Size { int(g.W), int(g.H), int(g.D) }
+}
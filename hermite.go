@@ -0,0 +1,128 @@
+package glvox
+
+import (
+	"math"
+)
+
+// Hermite is the surface description dual contouring works from: a sign
+// per point and, on segments whose ends differ in sign, the crossing
+// point with the surface normal there.
+type Hermite interface {
+	Inside(p Vec3) bool
+	Intersect(a, b Vec3) (p, n Vec3)
+}
+
+// Distancer is implemented by sources that know a lower bound of the
+// distance to their surface, negative inside. Dual contouring uses it to
+// skip whole octree nodes.
+type Distancer interface {
+	Distance(p Vec3) float32
+}
+
+// FieldHermite reads hermite data from a signed distance grid as made by
+// DistanceField; samples sit at voxel centers and are interpolated
+// trilinearly.
+type FieldHermite struct {
+	*FloatGrid
+}
+
+func (f FieldHermite) Distance(p Vec3) float32 {
+
+	g := f.FloatGrid
+	q := p.Minus(Vec3 { .5, .5, .5 }).Clamp(Vec3 {}, Vec3 { float32(g.W-1), float32(g.H-1), float32(g.D-1) })
+	x, y, z := int32(q.X), int32(q.Y), int32(q.Z)
+	if x >= g.W-1 && x > 0 { x-- }
+	if y >= g.H-1 && y > 0 { y-- }
+	if z >= g.D-1 && z > 0 { z-- }
+	tx, ty, tz := q.X - float32(x), q.Y - float32(y), q.Z - float32(z)
+
+	at := func(i, j, k int32) float32 {
+		if i >= g.W { i = g.W-1 }; if j >= g.H { j = g.H-1 }; if k >= g.D { k = g.D-1 }
+		return g.Get(i, j, k)
+	}
+	lerp := func(a, b, t float32) float32 { return a + (b - a)*t }
+
+	c00 := lerp(at(x, y, z), at(x+1, y, z), tx)
+	c10 := lerp(at(x, y+1, z), at(x+1, y+1, z), tx)
+	c01 := lerp(at(x, y, z+1), at(x+1, y, z+1), tx)
+	c11 := lerp(at(x, y+1, z+1), at(x+1, y+1, z+1), tx)
+	return lerp(lerp(c00, c10, ty), lerp(c01, c11, ty), tz)
+}
+
+func (f FieldHermite) Inside(p Vec3) bool {
+	return f.Distance(p) < 0
+}
+
+func (f FieldHermite) Intersect(a, b Vec3) (p, n Vec3) {
+	p = bisect(f.Inside, a, b)
+	n = gradient(f.Distance, p, .5)
+	return
+}
+
+// MeshHermite reads hermite data from a triangle soup whose normals point
+// outwards. The sign is taken from the nearest triangle; every query looks
+// at all triangles, so it suits small meshes.
+type MeshHermite []Tri
+
+func (m MeshHermite) Distance(p Vec3) float32 {
+
+	best := float32(math.Inf(1))
+	for i := range m {
+		d := m[i].SqrDistance(p)
+		if abs(d) < abs(best) { best = d }
+	}
+
+	if best < 0 { return -float32(math.Sqrt(float64(-best))) }
+	return float32(math.Sqrt(float64(best)))
+}
+
+func (m MeshHermite) Inside(p Vec3) bool {
+	return m.Distance(p) < 0
+}
+
+func (m MeshHermite) Intersect(a, b Vec3) (p, n Vec3) {
+
+	dir := b.Minus(a)
+	best := float32(2)
+	for i := range m {
+		t, hit := m[i].Intersect(a, dir)
+		if !hit || t < 0 || t > 1 || t >= best { continue }
+		best = t
+		n = m[i].Normal()
+	}
+
+	if best > 1 {
+		p = bisect(m.Inside, a, b)
+		n = gradient(m.Distance, p, dir.Norm() / 4)
+		return
+	}
+
+	p = a.Plus(dir.Mul(best))
+	return
+}
+
+// bisect finds the sign change of inside between a and b.
+func bisect(inside func(p Vec3) bool, a, b Vec3) Vec3 {
+	ina := inside(a)
+	for i := 0; i < 20; i++ {
+		m := a.Plus(b).Mul(.5)
+		if inside(m) == ina { a = m } else { b = m }
+	}
+	return a.Plus(b).Mul(.5)
+}
+
+// gradient returns the normalized central difference gradient of f at p.
+func gradient(f func(p Vec3) float32, p Vec3, h float32) Vec3 {
+	g := Vec3 {
+		f(p.Plus(Vec3 { h, 0, 0 })) - f(p.Minus(Vec3 { h, 0, 0 })),
+		f(p.Plus(Vec3 { 0, h, 0 })) - f(p.Minus(Vec3 { 0, h, 0 })),
+		f(p.Plus(Vec3 { 0, 0, h })) - f(p.Minus(Vec3 { 0, 0, h })),
+	}
+	if g.Dot(g) == 0 { return g }
+	return g.Normalize()
+}
+
+func abs(f float32) float32 {
+	if f < 0 { return -f }
+	return f
+}
@@ -0,0 +1,55 @@
+package glvox
+
+import (
+	"image"
+	"image/color"
+)
+
+// ImportHeightmap fills one column per pixel of img into dst, the image
+// lying in the xz plane and the columns growing along y. A pixel's
+// brightness scales its column from 0 to maxHeight voxels. Like
+// ReadBinvox the volume is placed at (offx, offy, offz).
+func ImportHeightmap(img image.Image, dst Setter, maxHeight int, v int,
+	offx, offy, offz int) {
+
+	b := img.Bounds()
+	for py := b.Min.Y; py < b.Max.Y; py++ {
+		for px := b.Min.X; px < b.Max.X; px++ {
+			g := color.Gray16Model.Convert(img.At(px, py)).(color.Gray16)
+			h := (int(g.Y) * maxHeight + 0x7fff) / 0xffff
+
+			x, z := px - b.Min.X, py - b.Min.Y
+			for y := 0; y < h; y++ {
+				dst.Set(offx + x, offy + y, offz + z, v)
+			}
+		}
+	}
+}
+
+// ImportMask extrudes the dark, opaque pixels of img depth voxels along z
+// into dst. The image lies in the xy plane with its top row at the
+// highest y. Like ReadBinvox the volume is placed at (offx, offy, offz).
+func ImportMask(img image.Image, dst Setter, depth int, v int,
+	offx, offy, offz int) {
+
+	b := img.Bounds()
+	h := b.Dy()
+	for py := b.Min.Y; py < b.Max.Y; py++ {
+		for px := b.Min.X; px < b.Max.X; px++ {
+			if !dark(img.At(px, py)) { continue }
+
+			x, y := px - b.Min.X, h-1 - (py - b.Min.Y)
+			for z := 0; z < depth; z++ {
+				dst.Set(offx + x, offy + y, offz + z, v)
+			}
+		}
+	}
+}
+
+// dark reports whether c is darker than mid grey and at least half opaque.
+func dark(c color.Color) bool {
+	_, _, _, a := c.RGBA()
+	if a < 0x8000 { return false }
+	g := color.Gray16Model.Convert(c).(color.Gray16)
+	return int(g.Y) * 0xffff < 0x8000 * int(a)
+}
@@ -0,0 +1,51 @@
+package glvox
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestImportHeightmap(t *testing.T) {
+
+	img := image.NewGray(image.Rect(10, 20, 13, 22))
+	img.SetGray(10, 20, color.Gray { 0 })
+	img.SetGray(11, 20, color.Gray { 128 })
+	img.SetGray(12, 21, color.Gray { 255 })
+
+	oct := NewOctree(32)
+	ImportHeightmap(img, oct, 8, 1, 1, 2, 3)
+
+	heights := []struct { x, z, h int }{ { 0, 0, 0 }, { 1, 0, 4 }, { 2, 1, 8 } }
+	for _, c := range heights {
+		top := 0
+		for y := 0; y < 16; y++ {
+			if v, _ := oct.Get(1 + c.x, 2 + y, 3 + c.z); v != 0 { top = y + 1 }
+		}
+		if top != c.h {
+			t.Errorf("(%d, %d): height %d expected, was %d", c.x, c.z, c.h, top)
+		}
+	}
+}
+
+func TestImportMask(t *testing.T) {
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for i := range img.Pix { img.Pix[i] = 255 }
+	img.Set(0, 0, color.Black)
+	img.Set(3, 2, color.Black)
+	img.Set(1, 1, color.NRGBA { 0, 0, 0, 10 })
+
+	oct := NewOctree(16)
+	ImportMask(img, oct, 3, 5, 0, 0, 4)
+
+	if n := count(OctreeVolume { oct }); n != 2*3 {
+		t.Errorf("6 voxels expected, was %d", n)
+	}
+	if v, _ := oct.Get(0, 2, 4); v != 5 {
+		t.Errorf("top left pixel expected at the top, was %d", v)
+	}
+	if v, _ := oct.Get(3, 0, 6); v != 5 {
+		t.Errorf("bottom right pixel expected at the bottom, was %d", v)
+	}
+}
@@ -0,0 +1,472 @@
+package glvox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// VoxModel is a MagicaVoxel model. Coordinates are MagicaVoxel's, z up;
+// each voxel is x, y, z and a palette index 1..255.
+type VoxModel struct {
+	Size [3]int
+	Voxels [][4]uint8
+}
+
+// VoxTransform maps model coordinates, relative to the model center, to
+// the scene: R*p + T.
+type VoxTransform struct {
+	R [3][3]int
+	T [3]int
+}
+
+// VoxInstance places a model in the scene.
+type VoxInstance struct {
+	Model int
+	VoxTransform
+}
+
+// VoxScene is the content of a .vox file. Palette[i] is the colour of
+// index i; index 0 is empty.
+type VoxScene struct {
+	Models []VoxModel
+	Instances []VoxInstance
+	Palette [256]color.RGBA
+}
+
+// VoxPalette returns the palette MagicaVoxel uses for files without an
+// RGBA chunk: a 6×6×6 colour cube followed by red, green, blue and grey
+// ramps.
+func VoxPalette() (pal [256]color.RGBA) {
+
+	i := 1
+	for r := 5; r >= 0; r-- {
+		for g := 5; g >= 0; g-- {
+			for b := 5; b >= 0; b-- {
+				if r + g + b == 0 { continue }
+				pal[i] = color.RGBA { uint8(r*0x33), uint8(g*0x33), uint8(b*0x33), 0xff }
+				i++
+			}
+		}
+	}
+
+	ramp := []uint8 { 0xee, 0xdd, 0xbb, 0xaa, 0x88, 0x77, 0x55, 0x44, 0x22, 0x11 }
+	for c := 0; c < 4; c++ {
+		for _, v := range ramp {
+			p := color.RGBA { A: 0xff }
+			if c == 0 || c == 3 { p.R = v }
+			if c == 1 || c == 3 { p.G = v }
+			if c == 2 || c == 3 { p.B = v }
+			pal[i] = p
+			i++
+		}
+	}
+
+	return
+}
+
+// voxToGL maps a MagicaVoxel cell, z up, to a glvox cell, y up.
+func voxToGL(p [3]int) [3]int {
+	return [3]int { p[0], p[2], -1 - p[1] }
+}
+
+func (t *VoxTransform) apply(p [3]int) (q [3]int) {
+	for i := range q {
+		q[i] = t.T[i]
+		for j := range p {
+			q[i] += t.R[i][j] * p[j]
+		}
+	}
+	return
+}
+
+// cells calls fn with the glvox cell and palette index of every voxel of
+// the scene.
+func (s *VoxScene) cells(fn func(p [3]int, c uint8)) {
+	for _, in := range s.Instances {
+		m := &s.Models[in.Model]
+		for _, v := range m.Voxels {
+			p := [3]int { int(v[0]) - m.Size[0]/2, int(v[1]) - m.Size[1]/2, int(v[2]) - m.Size[2]/2 }
+			fn(voxToGL(in.apply(p)), v[3])
+		}
+	}
+}
+
+// Bounds returns the glvox cells [lo, hi) covered by the models of the
+// scene, including their empty space.
+func (s *VoxScene) Bounds() (lo, hi [3]int) {
+
+	first := true
+	for _, in := range s.Instances {
+		m := &s.Models[in.Model]
+		for k := 0; k < 8; k++ {
+			var p [3]int
+			for i := range p {
+				if k >> uint(i) & 1 == 1 { p[i] = m.Size[i] - 1 }
+				p[i] -= m.Size[i]/2
+			}
+
+			q := voxToGL(in.apply(p))
+			for i := range q {
+				if first || q[i] < lo[i] { lo[i] = q[i] }
+				if first || q[i]+1 > hi[i] { hi[i] = q[i]+1 }
+			}
+			first = false
+		}
+	}
+
+	return
+}
+
+// Size is the size of the scene in glvox axes.
+func (s *VoxScene) Size() Size {
+	lo, hi := s.Bounds()
+	return Size { hi[0] - lo[0], hi[1] - lo[1], hi[2] - lo[2] }
+}
+
+// Load sets the voxels of all instances into dst, their palette index as
+// value. MagicaVoxel's z up becomes y up, and the lowest corner of the
+// scene is placed at (offx, offy, offz).
+func (s *VoxScene) Load(dst Setter, offx, offy, offz int) {
+
+	lo, _ := s.Bounds()
+	s.cells(func(p [3]int, c uint8) {
+		dst.Set(offx + p[0] - lo[0], offy + p[1] - lo[1], offz + p[2] - lo[2], int(c))
+	})
+}
+
+type voxChunk struct {
+	id string
+	content []byte
+}
+
+type voxNode struct {
+	typ string
+	attr map[string]string
+	children []int
+	models []int
+}
+
+// ReadVox reads a MagicaVoxel .vox file: its models, the scene graph
+// placing them and the palette. Unknown chunks are skipped; without a
+// scene graph every model is placed once at the origin.
+func ReadVox(r io.Reader) (scene *VoxScene, err error) {
+
+	buf := bufio.NewReader(r)
+
+	var head [8]byte
+	if _, err = io.ReadFull(buf, head[:]); err != nil || string(head[:4]) != "VOX " {
+		return nil, errors.New("not a vox file")
+	}
+
+	main, err := readVoxChunk(buf)
+	if err != nil { return nil, err }
+	if main.id != "MAIN" { return nil, fmt.Errorf("vox: MAIN chunk expected, was %q", main.id) }
+
+	var chunks []voxChunk
+	for {
+		c, err := readVoxChunk(buf)
+		if err == io.EOF { break }
+		if err != nil { return nil, err }
+		chunks = append(chunks, c)
+	}
+
+	scene = &VoxScene { Palette: VoxPalette() }
+	nodes := make(map[int]*voxNode)
+
+	for i, c := range chunks {
+		d := &voxData { c.content, nil }
+		switch c.id {
+		case "SIZE":
+			var m VoxModel
+			for k := range m.Size {
+				m.Size[k] = d.int()
+				if m.Size[k] <= 0 || m.Size[k] > 256 { d.fail("bad model size") }
+			}
+			scene.Models = append(scene.Models, m)
+		case "XYZI":
+			if len(scene.Models) == 0 { return nil, errors.New("vox: XYZI without SIZE") }
+			m := &scene.Models[len(scene.Models)-1]
+			n := d.int()
+			if n < 0 || n > len(d.b) / 4 { d.fail("bad voxel count"); break }
+			m.Voxels = make([][4]uint8, n)
+			for k := range m.Voxels {
+				copy(m.Voxels[k][:], d.b[4*k:])
+				v := m.Voxels[k]
+				if int(v[0]) >= m.Size[0] || int(v[1]) >= m.Size[1] || int(v[2]) >= m.Size[2] || v[3] == 0 {
+					d.fail("voxel outside of model")
+					break
+				}
+			}
+		case "RGBA":
+			if len(d.b) < 4*255 { d.fail("short palette") }
+			for k := 1; k < 256; k++ {
+				b := d.b[4*(k-1):]
+				scene.Palette[k] = color.RGBA { b[0], b[1], b[2], b[3] }
+			}
+		case "nTRN", "nGRP", "nSHP":
+			id := d.int()
+			n := &voxNode { typ: c.id, attr: d.dict() }
+			switch c.id {
+			case "nTRN":
+				n.children = []int { d.int() }
+				d.int(); d.int()		// reserved, layer
+				if d.int() > 0 {
+					for k, v := range d.dict() { n.attr[k] = v }
+				}
+			case "nGRP":
+				k := d.int()
+				for ; k > 0 && d.err == nil; k-- { n.children = append(n.children, d.int()) }
+			case "nSHP":
+				k := d.int()
+				for ; k > 0 && d.err == nil; k-- {
+					n.models = append(n.models, d.int())
+					d.dict()
+				}
+			}
+			nodes[id] = n
+		}
+		if d.err != nil { return nil, fmt.Errorf("vox: chunk %d %s: %v", i, c.id, d.err) }
+	}
+
+	if len(scene.Models) == 0 { return nil, errors.New("vox: no models") }
+
+	ident := VoxTransform { R: [3][3]int { { 1, 0, 0 }, { 0, 1, 0 }, { 0, 0, 1 } } }
+	if nodes[0] == nil {
+		for i, m := range scene.Models {
+			t := ident
+			t.T = [3]int { m.Size[0]/2, m.Size[1]/2, m.Size[2]/2 }
+			scene.Instances = append(scene.Instances, VoxInstance { i, t })
+		}
+		return scene, nil
+	}
+
+	if err = scene.walk(nodes, 0, ident, 0); err != nil { return nil, err }
+	return scene, nil
+}
+
+// walk collects the instances below node id, parent being the transform
+// of its ancestors.
+func (s *VoxScene) walk(nodes map[int]*voxNode, id int, parent VoxTransform, depth int) error {
+
+	n := nodes[id]
+	if n == nil { return fmt.Errorf("vox: missing node %d", id) }
+	if depth > 64 { return errors.New("vox: scene graph too deep") }
+
+	switch n.typ {
+	case "nTRN":
+		t, err := voxFrame(n.attr)
+		if err != nil { return fmt.Errorf("vox: node %d: %v", id, err) }
+
+		// parent after child
+		var c VoxTransform
+		c.T = parent.apply(t.T)
+		for i := range c.R {
+			for j := range c.R[i] {
+				for k := range c.R {
+					c.R[i][j] += parent.R[i][k] * t.R[k][j]
+				}
+			}
+		}
+		return s.walk(nodes, n.children[0], c, depth+1)
+	case "nGRP":
+		for _, c := range n.children {
+			if err := s.walk(nodes, c, parent, depth+1); err != nil { return err }
+		}
+	case "nSHP":
+		for _, m := range n.models {
+			if m < 0 || m >= len(s.Models) { return fmt.Errorf("vox: node %d: bad model %d", id, m) }
+			s.Instances = append(s.Instances, VoxInstance { m, parent })
+		}
+	}
+
+	return nil
+}
+
+// voxFrame parses the _r rotation and _t translation of a transform node.
+func voxFrame(attr map[string]string) (t VoxTransform, err error) {
+
+	r := 1<<2		// identity
+	if s, ok := attr["_r"]; ok {
+		if r, err = strconv.Atoi(s); err != nil { return }
+	}
+
+	// the column of the non-zero entry of the first two rows and the
+	// signs of all three
+	c0, c1 := r & 3, r >> 2 & 3
+	if c0 > 2 || c1 > 2 || c0 == c1 { return t, fmt.Errorf("bad rotation %d", r) }
+	cols := [3]int { c0, c1, 3 - c0 - c1 }
+	for i, c := range cols {
+		t.R[i][c] = 1
+		if r >> uint(4+i) & 1 == 1 { t.R[i][c] = -1 }
+	}
+
+	if s, ok := attr["_t"]; ok {
+		f := strings.Fields(s)
+		if len(f) != 3 { return t, fmt.Errorf("bad translation %q", s) }
+		for i := range t.T {
+			if t.T[i], err = strconv.Atoi(f[i]); err != nil { return }
+		}
+	}
+
+	return
+}
+
+func readVoxChunk(r io.Reader) (c voxChunk, err error) {
+
+	var head [12]byte
+	if _, err = io.ReadFull(r, head[:]); err != nil {
+		if err == io.ErrUnexpectedEOF { err = errors.New("vox: truncated chunk") }
+		return
+	}
+
+	// MAIN's children are read as the following chunks
+	c.id = string(head[:4])
+	n := int64(binary.LittleEndian.Uint32(head[4:]))
+	m := int64(binary.LittleEndian.Uint32(head[8:]))
+	if c.id == "MAIN" { m = 0 }
+
+	if c.content, err = ioutil.ReadAll(io.LimitReader(r, n + m)); err != nil { return }
+	if int64(len(c.content)) != n + m { return c, fmt.Errorf("vox: truncated %s chunk", c.id) }
+	c.content = c.content[:n]
+	return
+}
+
+// voxData reads the fields of a chunk; the first error sticks.
+type voxData struct {
+	b []byte
+	err error
+}
+
+func (d *voxData) fail(msg string) {
+	if d.err == nil { d.err = errors.New(msg) }
+	d.b = nil
+}
+
+func (d *voxData) int() int {
+	if len(d.b) < 4 { d.fail("truncated"); return 0 }
+	v := int32(binary.LittleEndian.Uint32(d.b))
+	d.b = d.b[4:]
+	return int(v)
+}
+
+func (d *voxData) string() string {
+	n := d.int()
+	if n < 0 || n > len(d.b) { d.fail("truncated"); return "" }
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+func (d *voxData) dict() map[string]string {
+	attr := make(map[string]string)
+	for n := d.int(); n > 0 && d.err == nil; n-- {
+		k := d.string()
+		attr[k] = d.string()
+	}
+	return attr
+}
+
+// WriteVox writes vol as a MagicaVoxel scene, split into models of at
+// most 256³ voxels placed by translation. Values are palette indices and
+// must lie in 0..255; palette may be nil for MagicaVoxel's default.
+// ReadVox and Load restore vol exactly.
+func WriteVox(w io.Writer, vol SizedGetter, palette *[256]color.RGBA) error {
+
+	s := vol.Size()
+	tile := func(n int) int { return (n + 255) / 256 }
+
+	var models, scene bytes.Buffer
+	le := binary.LittleEndian
+	put := func(b *bytes.Buffer, vs ...int) {
+		for _, v := range vs { binary.Write(b, le, int32(v)) }
+	}
+	chunk := func(b *bytes.Buffer, id string, content []byte) {
+		b.WriteString(id)
+		put(b, len(content), 0)
+		b.Write(content)
+	}
+
+	var ids []int
+	for tz := 0; tz < tile(s.D); tz++ {
+		for ty := 0; ty < tile(s.H); ty++ {
+			for tx := 0; tx < tile(s.W); tx++ {
+				x0, y0, z0 := tx*256, ty*256, tz*256
+				sw, sh, sd := s.W - x0, s.H - y0, s.D - z0
+				if sw > 256 { sw = 256 }; if sh > 256 { sh = 256 }; if sd > 256 { sd = 256 }
+
+				var xyzi bytes.Buffer
+				put(&xyzi, 0)
+				n := 0
+				for z := 0; z < sd; z++ {
+					for y := 0; y < sh; y++ {
+						for x := 0; x < sw; x++ {
+							v, _ := vol.Get(x0 + x, y0 + y, z0 + z)
+							if v == 0 { continue }
+							if v < 0 || v > 255 {
+								return fmt.Errorf("vox: value %d at (%d, %d, %d) is not a palette index",
+									v, x0 + x, y0 + y, z0 + z)
+							}
+							xyzi.Write([]byte { byte(x), byte(sd-1 - z), byte(y), byte(v) })
+							n++
+						}
+					}
+				}
+				b := xyzi.Bytes()
+				le.PutUint32(b, uint32(n))
+
+				var size bytes.Buffer
+				put(&size, sw, sd, sh)
+				chunk(&models, "SIZE", size.Bytes())
+				chunk(&models, "XYZI", b)
+
+				// the model center lands on the glvox cells of the tile
+				t := fmt.Sprintf("%d %d %d", x0 + sw/2, -z0 - sd + sd/2, y0 + sh/2)
+				var trn, shp bytes.Buffer
+				id := 2 + 2*len(ids)
+				put(&trn, id, 0, id+1, -1, 0, 1, 1, 2)
+				trn.WriteString("_t")
+				put(&trn, len(t))
+				trn.WriteString(t)
+				put(&shp, id+1, 0, 1, len(ids), 0)
+				chunk(&scene, "nTRN", trn.Bytes())
+				chunk(&scene, "nSHP", shp.Bytes())
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	var root, group, rgba bytes.Buffer
+	put(&root, 0, 0, 1, -1, -1, 1, 0)
+	put(&group, 1, 0, len(ids))
+	put(&group, ids...)
+
+	pal := VoxPalette()
+	if palette != nil { pal = *palette }
+	for _, c := range pal[1:] {
+		rgba.Write([]byte { c.R, c.G, c.B, c.A })
+	}
+	rgba.Write(make([]byte, 4))
+
+	var children bytes.Buffer
+	children.Write(models.Bytes())
+	chunk(&children, "nTRN", root.Bytes())
+	chunk(&children, "nGRP", group.Bytes())
+	children.Write(scene.Bytes())
+	chunk(&children, "RGBA", rgba.Bytes())
+
+	out := bufio.NewWriter(w)
+	out.WriteString("VOX ")
+	binary.Write(out, le, int32(150))
+	out.WriteString("MAIN")
+	binary.Write(out, le, [2]int32 { 0, int32(children.Len()) })
+	out.Write(children.Bytes())
+	return out.Flush()
+}
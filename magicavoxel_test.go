@@ -0,0 +1,142 @@
+package glvox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"testing"
+)
+
+func TestVoxRoundTrip(t *testing.T) {
+
+	// two models along x
+	src := GridVolume { NewGrid(300, 20, 10) }
+	for x := 0; x < 300; x += 7 {
+		src.Set(x, x % 20, x % 10, 1 + x % 255)
+	}
+	src.Set(299, 19, 9, 255)
+
+	pal := VoxPalette()
+	pal[3] = color.RGBA { 1, 2, 3, 4 }
+
+	var buf bytes.Buffer
+	if err := WriteVox(&buf, src, &pal); err != nil { t.Fatal(err) }
+
+	scene, err := ReadVox(&buf)
+	if err != nil { t.Fatal(err) }
+
+	if len(scene.Models) != 2 || len(scene.Instances) != 2 {
+		t.Fatalf("2 models expected, was %d with %d instances", len(scene.Models), len(scene.Instances))
+	}
+	if s := scene.Size(); s != (Size { 300, 20, 10 }) {
+		t.Errorf("size 300×20×10 expected, was %v", s)
+	}
+	if scene.Palette != pal {
+		t.Error("palette differs")
+	}
+
+	dst := GridVolume { NewGrid(300, 20, 10) }
+	scene.Load(dst, 0, 0, 0)
+	for z := 0; z < 10; z++ {
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 300; x++ {
+				exp, _ := src.Get(x, y, z)
+				if v, _ := dst.Get(x, y, z); v != exp {
+					t.Fatalf("(%d, %d, %d): expected %d, was %d", x, y, z, exp, v)
+				}
+			}
+		}
+	}
+
+	big := GridVolume { NewGrid(1, 1, 1) }
+	big.Set(0, 0, 0, 256)
+	if WriteVox(&buf, big, nil) == nil {
+		t.Error("error expected for a value beyond the palette")
+	}
+}
+
+// voxFile returns a .vox file of the chunks build adds to MAIN; ints are
+// written as int32, strings with their length.
+func voxFile(build func(chunk func(id string, vs ...interface{}))) *bytes.Buffer {
+
+	var children bytes.Buffer
+	build(func(id string, vs ...interface{}) {
+		var c bytes.Buffer
+		for _, v := range vs {
+			switch v := v.(type) {
+			case int: binary.Write(&c, binary.LittleEndian, int32(v))
+			case string: binary.Write(&c, binary.LittleEndian, int32(len(v))); c.WriteString(v)
+			case []byte: c.Write(v)
+			}
+		}
+		children.WriteString(id)
+		binary.Write(&children, binary.LittleEndian, [2]int32 { int32(c.Len()), 0 })
+		children.Write(c.Bytes())
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("VOX ")
+	binary.Write(&buf, binary.LittleEndian, [4]int32 { 150, 0x4e49414d, 0, int32(children.Len()) })
+	buf.Write(children.Bytes())
+	return &buf
+}
+
+func TestReadVoxTransform(t *testing.T) {
+
+	// a 2×1×1 model turned by 90° about MagicaVoxel's z axis
+	buf := voxFile(func(chunk func(id string, vs ...interface{})) {
+		chunk("SIZE", 2, 1, 1)
+		chunk("XYZI", 2, []byte { 0, 0, 0, 1,  1, 0, 0, 2 })
+		chunk("nTRN", 0, 0, 1, -1, -1, 1, 1, "_r", "17")
+		chunk("nSHP", 1, 0, 1, 0, 0)
+		chunk("LAYR", 0, 0, -1)
+	})
+
+	scene, err := ReadVox(buf)
+	if err != nil { t.Fatal(err) }
+
+	if s := scene.Size(); s != (Size { 1, 1, 2 }) {
+		t.Errorf("size 1×1×2 expected, was %v", s)
+	}
+	if scene.Palette != VoxPalette() {
+		t.Error("default palette expected")
+	}
+	if c := scene.Palette[1]; c != (color.RGBA { 0xff, 0xff, 0xff, 0xff }) {
+		t.Errorf("white expected, was %v", c)
+	}
+
+	oct := NewOctree(4)
+	scene.Load(oct, 0, 0, 0)
+	if v, _ := oct.Get(0, 0, 1); v != 1 {
+		t.Errorf("1 at (0, 0, 1) expected, was %d", v)
+	}
+	if v, _ := oct.Get(0, 0, 0); v != 2 {
+		t.Errorf("2 at (0, 0, 0) expected, was %d", v)
+	}
+
+	for _, bad := range []string { "", "VOX \x96\x00\x00\x00", "VOX \x96\x00\x00\x00MAIN\x00\x00\x00\x00\x10\x00\x00\x00SIZE" } {
+		if _, err := ReadVox(bytes.NewBufferString(bad)); err == nil {
+			t.Errorf("%q: error expected", bad)
+		}
+	}
+}
+
+func TestReadVoxBadXYZI(t *testing.T) {
+
+	// voxels outside the 2×1×1 model, without colour and a bad count, each
+	// followed by more voxels
+	for _, xyzi := range [][]interface{} {
+		{ 3, []byte { 5, 0, 0, 1,  0, 0, 0, 1,  1, 0, 0, 1 } },
+		{ 3, []byte { 0, 0, 0, 0,  0, 0, 0, 1,  1, 0, 0, 1 } },
+		{ -1, []byte { 0, 0, 0, 1 } },
+		{ 4, []byte { 0, 0, 0, 1 } },
+	} {
+		buf := voxFile(func(chunk func(id string, vs ...interface{})) {
+			chunk("SIZE", 2, 1, 1)
+			chunk("XYZI", xyzi...)
+		})
+		if _, err := ReadVox(buf); err == nil {
+			t.Errorf("%v: error expected", xyzi)
+		}
+	}
+}
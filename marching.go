@@ -0,0 +1,208 @@
+package glvox
+
+// Corner c of a cell is at (c&1, c>>1&1, c>>2&1), edge e joins the
+// corners mcEdges[e]. mcTable lists three edges per triangle for every
+// combination of inside corners. It is derived at startup by tracing the
+// surface around the cube faces; faces with two diagonal inside corners
+// always separate them, so neighbouring cells fit without cracks.
+var (
+	mcEdges [12][2]int
+	mcTable [256][]int
+)
+
+func init() {
+
+	e := 0
+	for a := 0; a < 8; a++ {
+		for bit := 1; bit < 8; bit <<= 1 {
+			if a & bit != 0 { continue }
+			mcEdges[e] = [2]int { a, a | bit }
+			e++
+		}
+	}
+
+	for c := range mcTable {
+		mcTable[c] = mcCase(c)
+	}
+}
+
+func mcCorner(c int) Vec3 {
+	return Vec3 { float32(c & 1), float32(c >> 1 & 1), float32(c >> 2 & 1) }
+}
+
+func mcEdge(a, b int) int {
+	if a > b { a, b = b, a }
+	for e, ab := range mcEdges {
+		if ab[0] == a && ab[1] == b { return e }
+	}
+	return -1
+}
+
+func mcCase(inside int) (tris []int) {
+
+	in := func(c int) bool { return inside >> uint(c) & 1 != 0 }
+	mid := func(e int) Vec3 {
+		return mcCorner(mcEdges[e][0]).Plus(mcCorner(mcEdges[e][1])).Mul(.5)
+	}
+
+	// segments on the faces, oriented to keep the inside on the left
+	// when seen from outside the cube
+	next := make(map[int]int)
+	for bit := 1; bit < 8; bit <<= 1 {
+		var uv []int
+		for b := 1; b < 8; b <<= 1 {
+			if b != bit { uv = append(uv, b) }
+		}
+		u, v := uv[0], uv[1]
+
+		for side := 0; side < 2; side++ {
+			base := bit * side
+			q := [4]int { base, base | u, base | u | v, base | v }
+			n := mcCorner(bit).Mul(float32(2*side - 1))
+
+			var crossed []int
+			for k := 0; k < 4; k++ {
+				if in(q[k]) != in(q[(k+1)%4]) { crossed = append(crossed, k) }
+			}
+
+			seg := func(ea, eb, c int) {
+				p, r := mid(ea), mid(eb)
+				if r.Minus(p).Cross(mcCorner(c).Minus(p)).Dot(n) < 0 { ea, eb = eb, ea }
+				next[ea] = eb
+			}
+
+			switch len(crossed) {
+			case 2:
+				k0, k1 := crossed[0], crossed[1]
+				ref := q[0]
+				for _, c := range q { if in(c) { ref = c } }
+				seg(mcEdge(q[k0], q[(k0+1)%4]), mcEdge(q[k1], q[(k1+1)%4]), ref)
+			case 4:
+				for k := 0; k < 4; k++ {
+					if !in(q[k]) { continue }
+					prev := (k+3) % 4
+					seg(mcEdge(q[prev], q[k]), mcEdge(q[k], q[(k+1)%4]), q[k])
+				}
+			}
+		}
+	}
+
+	// chain the segments into loops and fan them, reversed so that the
+	// triangles face away from the inside
+	done := make(map[int]bool)
+	for e := 0; e < 12; e++ {
+		if _, ok := next[e]; !ok || done[e] { continue }
+
+		var loop []int
+		for f := e; !done[f]; f = next[f] {
+			done[f] = true
+			loop = append(loop, f)
+		}
+
+		for i := 1; i+1 < len(loop); i++ {
+			tris = append(tris, loop[0], loop[i+1], loop[i])
+		}
+	}
+
+	return
+}
+
+// MarchingCubes extracts the surface between occupied (non-zero) and empty
+// voxels of vol. Samples sit at voxel centers and space outside the volume
+// counts as empty, so the mesh is closed. For octrees only the bounding
+// box of the occupied leaves is visited, and runs of 64 uniform cells are
+// skipped at once.
+func MarchingCubes(vol SizedGetter) *Mesh {
+
+	x0, y0, z0, x1, y1, z1 := bounds(vol, 0)
+	if x0 >= x1 || y0 >= y1 || z0 >= z1 { return new(Mesh) }
+
+	m := loadMask(vol, x0, y0, z0, x1, y1, z1).grow(1)
+
+	value := func(x, y, z int) float32 {
+		if m.get(x, y, z) { return 0 }
+		return 1
+	}
+
+	uniform := func(x, y, z int) bool {
+		if x & 63 != 0 || x + 64 >= m.w { return false }
+		i := x >> 6
+		w := m.row(y, z)[i]
+		if w != 0 && w != ^uint64(0) { return false }
+		b := m.get(x+64, y, z)
+		for _, r := range [][]uint64 { m.row(y+1, z), m.row(y, z+1), m.row(y+1, z+1) } {
+			if r[i] != w { return false }
+			if r[i+1] & 1 != 0 != b { return false }
+		}
+		return (w != 0) == b
+	}
+
+	return march(m.w-1, m.h-1, m.d-1, value, .5, uniform,
+		Vec3 { float32(m.x) + .5, float32(m.y) + .5, float32(m.z) + .5 })
+}
+
+// MarchingCubesField extracts the iso surface of g, with the inside below
+// iso as in a signed distance field. Samples sit at voxel centers and
+// samples outside the grid count as outside.
+func MarchingCubesField(g *FloatGrid, iso float32) *Mesh {
+
+	w, h, d := int(g.W), int(g.H), int(g.D)
+	value := func(x, y, z int) float32 {
+		x--; y--; z--
+		if x < 0 || x >= w || y < 0 || y >= h || z < 0 || z >= d { return iso + 1 }
+		return g.data[(z*h + y)*w + x]
+	}
+
+	return march(w+1, h+1, d+1, value, iso, nil, Vec3 { -.5, -.5, -.5 })
+}
+
+// march runs marching cubes over the cells [0,w)×[0,h)×[0,d), cell (x, y, z)
+// spanning the samples x..x+1, y..y+1, z..z+1. Sample positions are offset
+// by org. If uniform reports true at a cell the following 64 cells are
+// known to hold no surface.
+func march(w, h, d int, value func(x, y, z int) float32, iso float32,
+	uniform func(x, y, z int) bool, org Vec3) *Mesh {
+
+	mesh := new(Mesh)
+	verts := make(map[[4]int]int)
+
+	vertex := func(x, y, z int, e int) int {
+		a, b := mcEdges[e][0], mcEdges[e][1]
+		ax, ay, az := x + a&1, y + a>>1&1, z + a>>2&1
+		key := [4]int { ax, ay, az, b - a }
+		if i, ok := verts[key]; ok { return i }
+
+		fa := value(ax, ay, az)
+		fb := value(x + b&1, y + b>>1&1, z + b>>2&1)
+		t := float32(.5)
+		if fa != fb { t = (iso - fa) / (fb - fa) }
+
+		p := Vec3 { float32(ax), float32(ay), float32(az) }.Plus(
+			mcCorner(b).Minus(mcCorner(a)).Mul(t)).Plus(org)
+
+		i := len(mesh.Vertices)
+		mesh.Vertices = append(mesh.Vertices, p)
+		verts[key] = i
+		return i
+	}
+
+	for z := 0; z < d; z++ {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				if uniform != nil && uniform(x, y, z) { x += 63; continue }
+
+				c := 0
+				for k := 0; k < 8; k++ {
+					if value(x + k&1, y + k>>1&1, z + k>>2&1) < iso { c |= 1 << uint(k) }
+				}
+
+				for _, e := range mcTable[c] {
+					mesh.Indices = append(mesh.Indices, vertex(x, y, z, e))
+				}
+			}
+		}
+	}
+
+	mesh.smoothNormals()
+	return mesh
+}
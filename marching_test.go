@@ -0,0 +1,102 @@
+package glvox
+
+import (
+	"math"
+	"testing"
+)
+
+// checkClosed verifies that every edge of m is used exactly once in each
+// direction and returns the enclosed volume.
+func checkClosed(t *testing.T, m *Mesh) (vol float64) {
+
+	edges := make(map[[2]int]int)
+	for i := 0; i < len(m.Indices); i += 3 {
+		for k := 0; k < 3; k++ {
+			a, b := m.Indices[i+k], m.Indices[i+(k+1)%3]
+			edges[[2]int { a, b }]++
+		}
+		a, b, c := m.Vertices[m.Indices[i]], m.Vertices[m.Indices[i+1]], m.Vertices[m.Indices[i+2]]
+		vol += float64(a.Dot(b.Cross(c))) / 6
+	}
+
+	for e, n := range edges {
+		if n != 1 || edges[[2]int { e[1], e[0] }] != 1 {
+			t.Errorf("edge %v used %d times, reverse %d times", e, n, edges[[2]int { e[1], e[0] }])
+			return
+		}
+	}
+
+	if len(m.Normals) != len(m.Vertices) {
+		t.Errorf("%d normals expected, was %d", len(m.Vertices), len(m.Normals))
+	}
+
+	return
+}
+
+func TestMarchingCubesTable(t *testing.T) {
+
+	for c := 0; c < 256; c++ {
+		if len(mcTable[c]) % 3 != 0 {
+			t.Errorf("case %d: %d edges", c, len(mcTable[c]))
+		}
+		if (c == 0 || c == 255) != (len(mcTable[c]) == 0) {
+			t.Errorf("case %d: %d edges", c, len(mcTable[c]))
+		}
+	}
+
+	if n := len(mcTable[1]); n != 3 {
+		t.Errorf("one corner: 1 triangle expected, was %d", n/3)
+	}
+}
+
+func TestMarchingCubes(t *testing.T) {
+
+	oct := NewOctree(64)
+	oct.Set(20, 20, 20, 1)
+
+	m := MarchingCubes(OctreeVolume { oct })
+	if n := len(m.Indices) / 3; n != 8 {
+		t.Errorf("8 triangles expected for a single voxel, was %d", n)
+	}
+	if vol := checkClosed(t, m); math.Abs(vol - 1.0/6) > 1e-5 {
+		t.Errorf("volume 1/6 expected, was %f", vol)
+	}
+
+	// a checker board and the volume border stress ambiguous faces
+	g := GridVolume { NewGrid(6, 6, 6) }
+	for z := 0; z < 6; z++ {
+		for y := 0; y < 6; y++ {
+			for x := 0; x < 6; x++ {
+				if (x + y + z) % 2 == 0 || x == 5 { g.Set(x, y, z, 1) }
+			}
+		}
+	}
+	if vol := checkClosed(t, MarchingCubes(g)); vol <= 0 {
+		t.Errorf("positive volume expected, was %f", vol)
+	}
+}
+
+func TestMarchingCubesField(t *testing.T) {
+
+	g := GridVolume { NewGrid(12, 12, 12) }
+	for z := 3; z < 9; z++ {
+		for y := 3; y < 9; y++ {
+			for x := 3; x < 9; x++ {
+				g.Set(x, y, z, 1)
+			}
+		}
+	}
+
+	m := MarchingCubesField(DistanceField(g), 0)
+	vol := checkClosed(t, m)
+	if vol < 200 || vol > 216 {
+		t.Errorf("volume close to 216 expected, was %f", vol)
+	}
+
+	for i, p := range m.Vertices {
+		c := p.Minus(Vec3 { 6, 6, 6 })
+		if m.Normals[i].Dot(c) <= 0 {
+			t.Fatalf("normal %v at %v points inwards", m.Normals[i], p)
+		}
+	}
+}
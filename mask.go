@@ -0,0 +1,119 @@
+package glvox
+
+// mask is a bit per voxel occupancy of a box, packed along x.
+type mask struct {
+	x, y, z int
+	w, h, d int
+	stride int
+	bits []uint64
+}
+
+func newMask(x0, y0, z0, x1, y1, z1 int) *mask {
+	m := &mask { x: x0, y: y0, z: z0, w: x1-x0, h: y1-y0, d: z1-z0 }
+	m.stride = (m.w + 63) / 64
+	m.bits = make([]uint64, m.stride * m.h * m.d)
+	return m
+}
+
+// loadMask marks every voxel of the box with a non-zero value. Octrees
+// are read leaf by leaf, anything else voxel by voxel.
+func loadMask(vol Getter, x0, y0, z0, x1, y1, z1 int) *mask {
+
+	m := newMask(x0, y0, z0, x1, y1, z1)
+
+	if oct := octreeOf(vol); oct != nil {
+		oct.leaves(x0, y0, z0, x1, y1, z1, func(l leaf) {
+			if l.val == 0 { return }
+			m.fill(l.x, l.y, l.z, l.x+l.size, l.y+l.size, l.z+l.size)
+		})
+		return m
+	}
+
+	for z := z0; z < z1; z++ {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				if v, _ := vol.Get(x, y, z); v != 0 { m.set(x-x0, y-y0, z-z0) }
+			}
+		}
+	}
+
+	return m
+}
+
+func (m *mask) row(y, z int) []uint64 {
+	i := (z*m.h + y) * m.stride
+	return m.bits[i:i+m.stride]
+}
+
+func (m *mask) get(x, y, z int) bool {
+	return m.row(y, z)[x>>6] & (1 << uint(x&63)) != 0
+}
+
+func (m *mask) set(x, y, z int) {
+	m.row(y, z)[x>>6] |= 1 << uint(x&63)
+}
+
+// fill marks the box given in volume coordinates, clipped to the mask.
+func (m *mask) fill(x0, y0, z0, x1, y1, z1 int) {
+
+	x0 -= m.x; x1 -= m.x; if x0 < 0 { x0 = 0 }; if x1 > m.w { x1 = m.w }
+	y0 -= m.y; y1 -= m.y; if y0 < 0 { y0 = 0 }; if y1 > m.h { y1 = m.h }
+	z0 -= m.z; z1 -= m.z; if z0 < 0 { z0 = 0 }; if z1 > m.d { z1 = m.d }
+
+	for z := z0; z < z1; z++ {
+		for y := y0; y < y1; y++ {
+			r := m.row(y, z)
+			for x := x0; x < x1; {
+				if x&63 == 0 && x+64 <= x1 {
+					r[x>>6] = ^uint64(0)
+					x += 64
+					continue
+				}
+				r[x>>6] |= 1 << uint(x&63)
+				x++
+			}
+		}
+	}
+}
+
+// clearTail zeroes the padding bits behind the last voxel of every row.
+func (m *mask) clearTail() {
+	if m.w&63 == 0 || m.stride == 0 { return }
+	tail := uint64(1) << uint(m.w&63) - 1
+	for i := m.stride - 1; i < len(m.bits); i += m.stride {
+		m.bits[i] &= tail
+	}
+}
+
+// shift stores src moved by k bits towards higher x into dst, filling with 0.
+func shift(dst, src []uint64, k int) {
+
+	n := len(src)
+	q, r := k/64, uint(k%64)
+	if k < 0 { q, r = -k/64, uint(-k%64) }
+
+	for i := range dst {
+		var a, b uint64
+		if k >= 0 {
+			if j := i - q; j >= 0 && j < n { a = src[j] << r }
+			if j := i - q - 1; r != 0 && j >= 0 && j < n { b = src[j] >> (64 - r) }
+		} else {
+			if j := i + q; j < n { a = src[j] >> r }
+			if j := i + q + 1; r != 0 && j < n { b = src[j] << (64 - r) }
+		}
+		dst[i] = a | b
+	}
+}
+
+// grow returns a copy of m with n empty voxels of border on every side.
+func (m *mask) grow(n int) *mask {
+
+	g := newMask(m.x-n, m.y-n, m.z-n, m.x+m.w+n, m.y+m.h+n, m.z+m.d+n)
+	for z := 0; z < m.d; z++ {
+		for y := 0; y < m.h; y++ {
+			shift(g.row(y+n, z+n), m.row(y, z), n)
+		}
+	}
+
+	return g
+}
@@ -0,0 +1,41 @@
+package glvox
+
+// Mesh is an indexed triangle mesh; every three Indices form a triangle,
+// counter-clockwise when seen from outside. Values, if present, hold the
+// voxel value each vertex was made from.
+type Mesh struct {
+	Vertices []Vec3
+	Normals []Vec3
+	Values []int
+	Indices []int
+}
+
+func (m *Mesh) Tris() []Tri {
+	tris := make([]Tri, len(m.Indices)/3)
+	for i := range tris {
+		tris[i] = Tri {
+			m.Vertices[m.Indices[3*i]],
+			m.Vertices[m.Indices[3*i+1]],
+			m.Vertices[m.Indices[3*i+2]],
+		}
+	}
+	return tris
+}
+
+// smoothNormals sets every vertex normal to the area weighted average of
+// the normals of the triangles sharing it.
+func (m *Mesh) smoothNormals() {
+
+	m.Normals = make([]Vec3, len(m.Vertices))
+	for i := 0; i+2 < len(m.Indices); i += 3 {
+		a, b, c := m.Indices[i], m.Indices[i+1], m.Indices[i+2]
+		n := m.Vertices[b].Minus(m.Vertices[a]).Cross(m.Vertices[c].Minus(m.Vertices[a]))
+		m.Normals[a] = m.Normals[a].Plus(n)
+		m.Normals[b] = m.Normals[b].Plus(n)
+		m.Normals[c] = m.Normals[c].Plus(n)
+	}
+
+	for i, n := range m.Normals {
+		if n.Dot(n) > 0 { m.Normals[i] = n.Normalize() }
+	}
+}
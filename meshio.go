@@ -0,0 +1,288 @@
+package glvox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TriGroup names the triangles [Start, End) of a TriMesh.
+type TriGroup struct {
+	Name string
+	Start, End int
+}
+
+// TriMesh is a triangle soup read from a mesh file, with its groups and
+// bounding box.
+type TriMesh struct {
+	Tris []Tri
+	Groups []TriGroup
+	Lo, Hi Vec3
+}
+
+func (tm *TriMesh) group(name string) {
+	if n := len(tm.Groups); n > 0 {
+		g := &tm.Groups[n-1]
+		g.End = len(tm.Tris)
+		if g.Start == g.End { tm.Groups = tm.Groups[:n-1] }
+	}
+	tm.Groups = append(tm.Groups, TriGroup { name, len(tm.Tris), len(tm.Tris) })
+}
+
+func (tm *TriMesh) done() {
+	tm.group("")
+	tm.Groups = tm.Groups[:len(tm.Groups)-1]
+	tm.Lo, tm.Hi = Bounds(tm.Tris)
+}
+
+// ReadOBJ reads the faces of a Wavefront OBJ file. Polygons are split
+// into triangles by ear clipping; g and o statements start groups.
+// Texture coordinates, normals and materials are ignored.
+func ReadOBJ(r io.Reader) (tm *TriMesh, err error) {
+
+	tm = new(TriMesh)
+	tm.group("")
+
+	var verts []Vec3
+	var poly []Vec3
+	scan := bufio.NewScanner(r)
+	for line := 1; scan.Scan(); line++ {
+		f := strings.Fields(scan.Text())
+		if len(f) == 0 { continue }
+
+		switch f[0] {
+		case "v":
+			if len(f) < 4 { return nil, fmt.Errorf("obj line %d: too few coordinates", line) }
+			var c [3]float64
+			for i := range c {
+				if c[i], err = strconv.ParseFloat(f[i+1], 32); err != nil {
+					return nil, fmt.Errorf("obj line %d: %v", line, err)
+				}
+			}
+			verts = append(verts, Vec3 { float32(c[0]), float32(c[1]), float32(c[2]) })
+		case "f":
+			poly = poly[:0]
+			for _, v := range f[1:] {
+				// v, v/vt, v//vn or v/vt/vn; negative indices count back
+				i, err := strconv.Atoi(strings.SplitN(v, "/", 2)[0])
+				if i < 0 { i += len(verts) + 1 }
+				if err != nil || i < 1 || i > len(verts) {
+					return nil, fmt.Errorf("obj line %d: bad vertex %q", line, v)
+				}
+				poly = append(poly, verts[i-1])
+			}
+			if len(poly) < 3 { return nil, fmt.Errorf("obj line %d: face with %d vertices", line, len(poly)) }
+			tm.Tris = triangulate(poly, tm.Tris)
+		case "g", "o":
+			tm.group(strings.Join(f[1:], " "))
+		}
+	}
+	if err = scan.Err(); err != nil { return nil, err }
+
+	tm.done()
+	return tm, nil
+}
+
+// triangulate appends the triangles of the simple polygon poly to tris,
+// clipping ears in the plane poly is most parallel to.
+func triangulate(poly []Vec3, tris []Tri) []Tri {
+
+	if len(poly) == 3 { return append(tris, Tri { poly[0], poly[1], poly[2] }) }
+
+	// Newell's normal picks the projection and the winding
+	var n Vec3
+	for i, a := range poly {
+		b := poly[(i+1) % len(poly)]
+		n = n.Plus(Vec3 { (a.Y - b.Y) * (a.Z + b.Z), (a.Z - b.Z) * (a.X + b.X), (a.X - b.X) * (a.Y + b.Y) })
+	}
+
+	nz := n.Z
+	uv := func(p Vec3) (float32, float32) { return p.X, p.Y }
+	switch {
+	case abs(n.X) >= abs(n.Y) && abs(n.X) >= abs(n.Z):
+		uv = func(p Vec3) (float32, float32) { return p.Y, p.Z }
+		nz = n.X
+	case abs(n.Y) >= abs(n.Z):
+		uv = func(p Vec3) (float32, float32) { return p.Z, p.X }
+		nz = n.Y
+	}
+
+	cross := func(a, b, c Vec3) float32 {
+		ax, ay := uv(a); bx, by := uv(b); cx, cy := uv(c)
+		d := (bx-ax)*(cy-ay) - (by-ay)*(cx-ax)
+		if nz < 0 { d = -d }
+		return d
+	}
+
+	idx := make([]int, len(poly))
+	for i := range idx { idx[i] = i }
+
+	for len(idx) > 3 {
+		ear := -1
+		for k := range idx {
+			a, b, c := poly[idx[(k+len(idx)-1) % len(idx)]], poly[idx[k]], poly[idx[(k+1) % len(idx)]]
+			if cross(a, b, c) <= 0 { continue }
+
+			inside := false
+			for _, j := range idx {
+				p := poly[j]
+				if p == a || p == b || p == c { continue }
+				if cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0 {
+					inside = true
+					break
+				}
+			}
+			if !inside { ear = k; break }
+		}
+
+		// degenerate or self intersecting: cut any corner
+		if ear < 0 { ear = 0 }
+
+		tris = append(tris, Tri {
+			poly[idx[(ear+len(idx)-1) % len(idx)]], poly[idx[ear]], poly[idx[(ear+1) % len(idx)]] })
+		idx = append(idx[:ear], idx[ear+1:]...)
+	}
+
+	return append(tris, Tri { poly[idx[0]], poly[idx[1]], poly[idx[2]] })
+}
+
+// ReadSTL reads an ASCII or binary STL file. Files are binary if their
+// length matches the triangle count of the binary header, since binary
+// headers may start with "solid" too. Every ASCII solid is a group.
+func ReadSTL(r io.Reader) (tm *TriMesh, err error) {
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil { return nil, err }
+
+	tm = new(TriMesh)
+	if len(data) >= 84 {
+		n := int64(binary.LittleEndian.Uint32(data[80:]))
+		if int64(len(data)) == 84 + 50*n {
+			tm.Tris = make([]Tri, n)
+			for i := range tm.Tris {
+				// skip the normal
+				f := data[84 + 50*i + 12:]
+				for k := 0; k < 9; k++ {
+					v := math.Float32frombits(binary.LittleEndian.Uint32(f[4*k:]))
+					switch k % 3 {
+					case 0: tm.Tris[i][k/3].X = v
+					case 1: tm.Tris[i][k/3].Y = v
+					case 2: tm.Tris[i][k/3].Z = v
+					}
+				}
+			}
+			tm.done()
+			return tm, nil
+		}
+	}
+
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("solid")) {
+		return nil, errors.New("not a stl file")
+	}
+
+	var tri Tri
+	k := 0
+	tm.group("")
+	scan := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scan.Scan(); line++ {
+		f := strings.Fields(scan.Text())
+		if len(f) == 0 { continue }
+
+		switch f[0] {
+		case "solid":
+			tm.group(strings.Join(f[1:], " "))
+		case "vertex":
+			if len(f) != 4 || k == 3 { return nil, fmt.Errorf("stl line %d: bad vertex", line) }
+			var c [3]float64
+			for i := range c {
+				if c[i], err = strconv.ParseFloat(f[i+1], 32); err != nil {
+					return nil, fmt.Errorf("stl line %d: %v", line, err)
+				}
+			}
+			tri[k] = Vec3 { float32(c[0]), float32(c[1]), float32(c[2]) }
+			k++
+		case "endloop":
+			if k != 3 { return nil, fmt.Errorf("stl line %d: facet with %d vertices", line, k) }
+			tm.Tris = append(tm.Tris, tri)
+			k = 0
+		}
+	}
+	if err = scan.Err(); err != nil { return nil, err }
+
+	tm.done()
+	return tm, nil
+}
+
+// WriteSTL writes the triangles of m as binary STL, or as ASCII STL
+// named name if ascii is set. Facet normals are computed from the
+// vertices.
+func WriteSTL(w io.Writer, m *Mesh, name string, ascii bool) error {
+
+	buf := bufio.NewWriter(w)
+	tris := m.Tris()
+
+	if ascii {
+		fmt.Fprintf(buf, "solid %s\n", name)
+		for i := range tris {
+			n := tris[i].Normal()
+			fmt.Fprintf(buf, "facet normal %g %g %g\n outer loop\n", n.X, n.Y, n.Z)
+			for _, p := range tris[i] {
+				fmt.Fprintf(buf, "  vertex %g %g %g\n", p.X, p.Y, p.Z)
+			}
+			fmt.Fprintf(buf, " endloop\nendfacet\n")
+		}
+		fmt.Fprintf(buf, "endsolid %s\n", name)
+		return buf.Flush()
+	}
+
+	var head [84]byte
+	copy(head[:80], name)
+	binary.LittleEndian.PutUint32(head[80:], uint32(len(tris)))
+	buf.Write(head[:])
+
+	var facet [50]byte
+	for i := range tris {
+		n := tris[i].Normal()
+		f := [12]float32 { n.X, n.Y, n.Z }
+		for k, p := range tris[i] {
+			f[3+3*k], f[4+3*k], f[5+3*k] = p.X, p.Y, p.Z
+		}
+		for k, v := range f {
+			binary.LittleEndian.PutUint32(facet[4*k:], math.Float32bits(v))
+		}
+		buf.Write(facet[:])
+	}
+
+	return buf.Flush()
+}
+
+// WriteOBJ writes m as Wavefront OBJ, with vertex normals if m has them.
+func WriteOBJ(w io.Writer, m *Mesh) error {
+
+	buf := bufio.NewWriter(w)
+	for _, v := range m.Vertices {
+		fmt.Fprintf(buf, "v %g %g %g\n", v.X, v.Y, v.Z)
+	}
+	for _, n := range m.Normals {
+		fmt.Fprintf(buf, "vn %g %g %g\n", n.X, n.Y, n.Z)
+	}
+
+	normals := len(m.Normals) == len(m.Vertices)
+	for i := 0; i+2 < len(m.Indices); i += 3 {
+		a, b, c := m.Indices[i]+1, m.Indices[i+1]+1, m.Indices[i+2]+1
+		if normals {
+			fmt.Fprintf(buf, "f %d//%d %d//%d %d//%d\n", a, a, b, b, c, c)
+		} else {
+			fmt.Fprintf(buf, "f %d %d %d\n", a, b, c)
+		}
+	}
+
+	return buf.Flush()
+}
@@ -0,0 +1,110 @@
+package glvox
+
+import (
+	"bytes"
+	"testing"
+)
+
+func area(tris []Tri) (a float32) {
+	for i := range tris {
+		a += tris[i][1].Minus(tris[i][0]).Cross(tris[i][2].Minus(tris[i][0])).Norm() / 2
+	}
+	return
+}
+
+func TestReadOBJ(t *testing.T) {
+
+	obj := `# an L shaped hexagon, a quad and a triangle
+v 0 0 0
+v 2 0 0
+v 2 1 0
+v 1 1 0
+v 1 2 0
+v 0 2 0
+g floor
+f 1 2 3 4 5 6
+o box
+v 0 0 1
+v 1 0 1
+v 1 1 1
+v 0 1 1
+f -4/1/1 -3/2/1 -2/3/1 -1/4/1
+f 7//1 8//1 9//1
+`
+	tm, err := ReadOBJ(bytes.NewBufferString(obj))
+	if err != nil { t.Fatal(err) }
+
+	if len(tm.Tris) != 7 {
+		t.Fatalf("7 triangles expected, was %d", len(tm.Tris))
+	}
+	if a := area(tm.Tris[:4]); a != 3 {
+		t.Errorf("area 3 of the concave hexagon expected, was %g", a)
+	}
+	for i := range tm.Tris[:6] {
+		if n := tm.Tris[i].Normal(); n != (Vec3 { 0, 0, 1 }) {
+			t.Errorf("triangle %d: winding of the polygon expected, normal was %v", i, n)
+		}
+	}
+	if len(tm.Groups) != 2 || tm.Groups[0] != (TriGroup { "floor", 0, 4 }) || tm.Groups[1] != (TriGroup { "box", 4, 7 }) {
+		t.Errorf("unexpected groups %v", tm.Groups)
+	}
+	if tm.Lo != (Vec3 { 0, 0, 0 }) || tm.Hi != (Vec3 { 2, 2, 1 }) {
+		t.Errorf("unexpected bounds %v %v", tm.Lo, tm.Hi)
+	}
+
+	for _, bad := range []string { "v 1 2\n", "v 0 0 0\nf 1 2 3\n", "v 0 0 0\nf 1 1\n" } {
+		if _, err := ReadOBJ(bytes.NewBufferString(bad)); err == nil {
+			t.Errorf("%q: error expected", bad)
+		}
+	}
+}
+
+func TestMeshFiles(t *testing.T) {
+
+	oct := NewOctree(8)
+	oct.SetBox(2, 2, 2, 5, 6, 7, 1)
+	m := GreedyMesh(OctreeVolume { oct })
+	m.smoothNormals()
+	tris := m.Tris()
+
+	for _, ascii := range []bool { false, true } {
+		var buf bytes.Buffer
+		if err := WriteSTL(&buf, m, "box", ascii); err != nil { t.Fatal(err) }
+
+		tm, err := ReadSTL(&buf)
+		if err != nil { t.Fatal(err) }
+		if len(tm.Tris) != len(tris) || tm.Tris[5] != tris[5] {
+			t.Errorf("ascii %v: triangles differ", ascii)
+		}
+		if tm.Lo != (Vec3 { 2, 2, 2 }) || tm.Hi != (Vec3 { 5, 6, 7 }) {
+			t.Errorf("ascii %v: unexpected bounds %v %v", ascii, tm.Lo, tm.Hi)
+		}
+		if ascii && (len(tm.Groups) != 1 || tm.Groups[0].Name != "box") {
+			t.Errorf("solid box expected, was %v", tm.Groups)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteOBJ(&buf, m); err != nil { t.Fatal(err) }
+	if !bytes.Contains(buf.Bytes(), []byte("\nvn ")) || !bytes.Contains(buf.Bytes(), []byte("//")) {
+		t.Error("vertex normals expected")
+	}
+
+	tm, err := ReadOBJ(&buf)
+	if err != nil { t.Fatal(err) }
+	if len(tm.Tris) != len(tris) || tm.Tris[7] != tris[7] {
+		t.Error("triangles differ")
+	}
+
+	// a triangle without area gets a zero normal, not NaN
+	flat := &Mesh { Vertices: []Vec3 { { 0, 0, 0 }, { 1, 1, 1 }, { 2, 2, 2 } }, Indices: []int { 0, 1, 2 } }
+	buf.Reset()
+	if err := WriteSTL(&buf, flat, "flat", true); err != nil { t.Fatal(err) }
+	if bytes.Contains(bytes.ToLower(buf.Bytes()), []byte("nan")) || !bytes.Contains(buf.Bytes(), []byte("facet normal 0 0 0")) {
+		t.Errorf("zero normal expected, was\n%s", buf.Bytes())
+	}
+
+	if _, err := ReadSTL(bytes.NewBufferString("ply\n")); err == nil {
+		t.Error("error expected for a file that is not stl")
+	}
+}
@@ -0,0 +1,167 @@
+package glvox
+
+import (
+	"math"
+	"math/bits"
+)
+
+type Shape int
+
+const (
+	Cube Shape = iota
+	Sphere
+	Cross
+)
+
+// Element is a structuring element centered on the voxel.
+type Element struct {
+	Shape Shape
+	Radius int
+}
+
+// span is the part of an element in row (dy, dz): x from -w to w.
+type span struct {
+	dy, dz, w int
+}
+
+func (e Element) spans() (s []span) {
+
+	r := e.Radius
+	for dz := -r; dz <= r; dz++ {
+		for dy := -r; dy <= r; dy++ {
+			switch e.Shape {
+			case Cube:
+				s = append(s, span { dy, dz, r })
+			case Sphere:
+				q := r*r - dy*dy - dz*dz
+				if q < 0 { continue }
+				s = append(s, span { dy, dz, int(math.Sqrt(float64(q))) })
+			case Cross:
+				if dy == 0 && dz == 0 {
+					s = append(s, span { 0, 0, r })
+				} else if dy == 0 || dz == 0 {
+					s = append(s, span { dy, dz, 0 })
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// Dilate grows the non-zero voxels of vol by e. New voxels get value v.
+func Dilate(vol SizedGetSetter, e Element, v int) {
+	morph(vol, e, v, func(m *mask) *mask { return dilate(m, e) })
+}
+
+// Erode shrinks the non-zero voxels of vol by e. Voxels outside the volume
+// count as empty, so solids touching the border are eroded there too.
+func Erode(vol SizedGetSetter, e Element) {
+	morph(vol, e, 0, func(m *mask) *mask { return erode(m, e) })
+}
+
+// Open erodes and then dilates, removing parts thinner than e.
+// Surviving voxels keep their values.
+func Open(vol SizedGetSetter, e Element) {
+	morph(vol, e, 0, func(m *mask) *mask {
+		o := dilate(erode(m, e), e)
+		for i := range o.bits { o.bits[i] &= m.bits[i] }
+		return o
+	})
+}
+
+// Close dilates and then erodes, filling gaps narrower than e with v.
+func Close(vol SizedGetSetter, e Element, v int) {
+	morph(vol, e, v, func(m *mask) *mask {
+		c := erode(dilate(m, e), e)
+		for i := range c.bits { c.bits[i] |= m.bits[i] }
+		return c
+	})
+}
+
+// morph applies op to the occupancy of vol and writes back the difference.
+func morph(vol SizedGetSetter, e Element, v int, op func(m *mask) *mask) {
+
+	x0, y0, z0, x1, y1, z1 := bounds(vol, e.Radius)
+	if x0 >= x1 || y0 >= y1 || z0 >= z1 { return }
+
+	src := loadMask(vol, x0, y0, z0, x1, y1, z1)
+	dst := op(src)
+
+	for z := 0; z < src.d; z++ {
+		for y := 0; y < src.h; y++ {
+			a, b := src.row(y, z), dst.row(y, z)
+			for i := range a {
+				diff := a[i] ^ b[i]
+				for diff != 0 {
+					bit := diff & -diff
+					diff ^= bit
+					x := i*64 + bits.TrailingZeros64(bit)
+					if b[i] & bit != 0 {
+						vol.Set(x0+x, y0+y, z0+z, v)
+					} else {
+						vol.Set(x0+x, y0+y, z0+z, 0)
+					}
+				}
+			}
+		}
+	}
+}
+
+func dilate(m *mask, e Element) *mask {
+	return combine(m, e, false)
+}
+
+func erode(m *mask, e Element) *mask {
+	return combine(m, e, true)
+}
+
+// combine ORs (dilation) or ANDs (erosion) the element spans over m. Each
+// distinct span width is applied along x once, rows are then combined.
+func combine(m *mask, e Element, and bool) *mask {
+
+	spans := e.spans()
+	rows := make(map[int]*mask)
+	tmp := make([]uint64, m.stride)
+	for _, s := range spans {
+		if rows[s.w] != nil { continue }
+
+		r := newMask(m.x, m.y, m.z, m.x+m.w, m.y+m.h, m.z+m.d)
+		if and { for i := range r.bits { r.bits[i] = ^uint64(0) } }
+		for i := 0; i < len(m.bits); i += m.stride {
+			src, dst := m.bits[i:i+m.stride], r.bits[i:i+m.stride]
+			for k := -s.w; k <= s.w; k++ {
+				shift(tmp, src, k)
+				for j := range dst {
+					if and { dst[j] &= tmp[j] } else { dst[j] |= tmp[j] }
+				}
+			}
+		}
+		r.clearTail()
+		rows[s.w] = r
+	}
+
+	out := newMask(m.x, m.y, m.z, m.x+m.w, m.y+m.h, m.z+m.d)
+	for z := 0; z < m.d; z++ {
+		for y := 0; y < m.h; y++ {
+			dst := out.row(y, z)
+			if and { for j := range dst { dst[j] = ^uint64(0) } }
+
+			for _, s := range spans {
+				sy, sz := y+s.dy, z+s.dz
+				if sy < 0 || sy >= m.h || sz < 0 || sz >= m.d {
+					if and { for j := range dst { dst[j] = 0 } }
+					continue
+				}
+
+				src := rows[s.w].row(sy, sz)
+				for j := range dst {
+					if and { dst[j] &= src[j] } else { dst[j] |= src[j] }
+				}
+			}
+		}
+	}
+	out.clearTail()
+
+	return out
+}
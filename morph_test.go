@@ -0,0 +1,81 @@
+package glvox
+
+import (
+	"testing"
+)
+
+func count(vol SizedGetSetter) (n int) {
+	s := vol.Size()
+	for z := 0; z < s.D; z++ {
+		for y := 0; y < s.H; y++ {
+			for x := 0; x < s.W; x++ {
+				if v, _ := vol.Get(x, y, z); v != 0 { n++ }
+			}
+		}
+	}
+	return
+}
+
+func TestDilateErode(t *testing.T) {
+
+	tests := []struct {
+		e Element
+		n int
+	}{
+		{ Element { Cube, 1 }, 27 },
+		{ Element { Cube, 2 }, 125 },
+		{ Element { Sphere, 1 }, 7 },
+		{ Element { Sphere, 2 }, 33 },
+		{ Element { Cross, 2 }, 13 },
+	}
+
+	for _, tt := range tests {
+		vols := []SizedGetSetter { OctreeVolume { NewOctree(16) }, GridVolume { NewGrid(16, 16, 16) } }
+		for _, vol := range vols {
+			vol.Set(7, 7, 7, 1)
+
+			Dilate(vol, tt.e, 2)
+			if n := count(vol); n != tt.n {
+				t.Errorf("%T %v: %d voxels expected, was %d", vol, tt.e, tt.n, n)
+			}
+			if v, _ := vol.Get(7, 7, 7); v != 1 {
+				t.Errorf("%T %v: center must keep value 1, was %d", vol, tt.e, v)
+			}
+
+			Erode(vol, tt.e)
+			if n := count(vol); n != 1 {
+				t.Errorf("%T %v: erode back to 1 voxel expected, was %d", vol, tt.e, n)
+			}
+		}
+	}
+}
+
+func TestOpenClose(t *testing.T) {
+
+	vol := GridVolume { NewGrid(16, 16, 16) }
+	for z := 2; z < 8; z++ {
+		for y := 2; y < 8; y++ {
+			for x := 2; x < 14; x++ {
+				if x == 7 { continue }
+				vol.Set(x, y, z, 1)
+			}
+		}
+	}
+	vol.Set(10, 12, 4, 1)
+
+	Close(vol, Element { Cube, 1 }, 2)
+	if v, _ := vol.Get(7, 4, 4); v != 2 {
+		t.Errorf("gap closed with 2 expected, was %d", v)
+	}
+	if n := count(vol); n != 12*6*6 + 1 {
+		t.Errorf("%d voxels after close expected, was %d", 12*6*6 + 1, n)
+	}
+
+	Open(vol, Element { Cube, 1 })
+	if v, _ := vol.Get(10, 12, 4); v != 0 {
+		t.Errorf("stray voxel removed expected, was %d", v)
+	}
+	if n := count(vol); n != 12*6*6 {
+		t.Errorf("%d voxels after open expected, was %d", 12*6*6, n)
+	}
+}
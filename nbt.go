@@ -0,0 +1,141 @@
+package glvox
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// NBT tag types. Payloads decode to int8, int16, int32, int64, float32,
+// float64, []byte, string, []interface{}, map[string]interface{},
+// []int32 and []int64.
+const (
+	TagEnd = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+const nbtMaxDepth = 512
+
+// ReadNBT reads a named binary tag file, big endian, gzip compressed or
+// not, and returns the name and content of its root compound.
+func ReadNBT(r io.Reader) (name string, root map[string]interface{}, err error) {
+
+	buf := bufio.NewReader(r)
+	if magic, _ := buf.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		z, err := gzip.NewReader(buf)
+		if err != nil { return "", nil, err }
+		defer z.Close()
+		buf = bufio.NewReader(z)
+	}
+
+	d := nbtDecoder { r: buf }
+	if typ := d.byte(); typ != TagCompound {
+		if d.err != nil { return "", nil, d.err }
+		return "", nil, errors.New("nbt: root is not a compound")
+	}
+
+	name = d.string()
+	v := d.payload(TagCompound, 0)
+	if d.err != nil { return "", nil, d.err }
+	return name, v.(map[string]interface{}), nil
+}
+
+// nbtDecoder reads tags; the first error sticks and ends decoding.
+type nbtDecoder struct {
+	r io.Reader
+	err error
+	b [8]byte
+}
+
+func (d *nbtDecoder) read(n int) []byte {
+	if d.err != nil { return d.b[:n] }
+	if _, err := io.ReadFull(d.r, d.b[:n]); err != nil {
+		d.err = fmt.Errorf("nbt: %v", err)
+		if err == io.EOF { d.err = errors.New("nbt: truncated") }
+	}
+	return d.b[:n]
+}
+
+func (d *nbtDecoder) byte() byte { return d.read(1)[0] }
+func (d *nbtDecoder) short() int16 { return int16(binary.BigEndian.Uint16(d.read(2))) }
+func (d *nbtDecoder) int() int32 { return int32(binary.BigEndian.Uint32(d.read(4))) }
+func (d *nbtDecoder) long() int64 { return int64(binary.BigEndian.Uint64(d.read(8))) }
+
+// bytes reads n bytes without trusting n for the allocation.
+func (d *nbtDecoder) bytes(n int64) []byte {
+	if d.err != nil { return nil }
+	if n < 0 { d.err = errors.New("nbt: negative length"); return nil }
+	b, err := ioutil.ReadAll(io.LimitReader(d.r, n))
+	if err == nil && int64(len(b)) < n { err = errors.New("truncated") }
+	if err != nil { d.err = fmt.Errorf("nbt: %v", err) }
+	return b
+}
+
+func (d *nbtDecoder) string() string {
+	return string(d.bytes(int64(uint16(d.short()))))
+}
+
+func (d *nbtDecoder) payload(typ byte, depth int) interface{} {
+
+	if depth > nbtMaxDepth {
+		if d.err == nil { d.err = errors.New("nbt: nested too deep") }
+		return nil
+	}
+
+	switch typ {
+	case TagByte: return int8(d.byte())
+	case TagShort: return d.short()
+	case TagInt: return d.int()
+	case TagLong: return d.long()
+	case TagFloat: return math.Float32frombits(uint32(d.int()))
+	case TagDouble: return math.Float64frombits(uint64(d.long()))
+	case TagByteArray: return d.bytes(int64(d.int()))
+	case TagString: return d.string()
+	case TagIntArray:
+		b := d.bytes(4 * int64(d.int()))
+		a := make([]int32, len(b)/4)
+		for i := range a { a[i] = int32(binary.BigEndian.Uint32(b[4*i:])) }
+		return a
+	case TagLongArray:
+		b := d.bytes(8 * int64(d.int()))
+		a := make([]int64, len(b)/8)
+		for i := range a { a[i] = int64(binary.BigEndian.Uint64(b[8*i:])) }
+		return a
+	case TagList:
+		elem, n := d.byte(), d.int()
+		var list []interface{}
+		// lists of End have no payload to bound the count
+		for i := int32(0); i < n && elem != TagEnd && d.err == nil; i++ {
+			list = append(list, d.payload(elem, depth+1))
+		}
+		return list
+	case TagCompound:
+		c := make(map[string]interface{})
+		for d.err == nil {
+			t := d.byte()
+			if t == TagEnd { break }
+			name := d.string()
+			c[name] = d.payload(t, depth+1)
+		}
+		return c
+	}
+
+	if d.err == nil { d.err = fmt.Errorf("nbt: unknown tag type %d", typ) }
+	return nil
+}
@@ -0,0 +1,88 @@
+package glvox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// writeNBT encodes v as tag payload; the inverse of nbtDecoder.
+func writeNBT(buf *bytes.Buffer, v interface{}) byte {
+	be := binary.BigEndian
+	str := func(s string) { binary.Write(buf, be, uint16(len(s))); buf.WriteString(s) }
+
+	switch v := v.(type) {
+	case int8: buf.WriteByte(byte(v)); return TagByte
+	case int16: binary.Write(buf, be, v); return TagShort
+	case int32: binary.Write(buf, be, v); return TagInt
+	case int64: binary.Write(buf, be, v); return TagLong
+	case float32: binary.Write(buf, be, v); return TagFloat
+	case float64: binary.Write(buf, be, v); return TagDouble
+	case []byte: binary.Write(buf, be, int32(len(v))); buf.Write(v); return TagByteArray
+	case string: str(v); return TagString
+	case []int32: binary.Write(buf, be, int32(len(v))); binary.Write(buf, be, v); return TagIntArray
+	case []int64: binary.Write(buf, be, int32(len(v))); binary.Write(buf, be, v); return TagLongArray
+	case []interface{}:
+		var elems bytes.Buffer
+		typ := byte(TagEnd)
+		for _, e := range v { typ = writeNBT(&elems, e) }
+		buf.WriteByte(typ)
+		binary.Write(buf, be, int32(len(v)))
+		buf.Write(elems.Bytes())
+		return TagList
+	case map[string]interface{}:
+		for k, e := range v {
+			var payload bytes.Buffer
+			buf.WriteByte(writeNBT(&payload, e))
+			str(k)
+			buf.Write(payload.Bytes())
+		}
+		buf.WriteByte(TagEnd)
+		return TagCompound
+	}
+	panic("nbt: unsupported type")
+}
+
+func nbtFile(name string, root map[string]interface{}, compress bool) *bytes.Buffer {
+	var raw bytes.Buffer
+	raw.WriteByte(TagCompound)
+	binary.Write(&raw, binary.BigEndian, uint16(len(name)))
+	raw.WriteString(name)
+	writeNBT(&raw, root)
+	if !compress { return &raw }
+
+	var buf bytes.Buffer
+	z := gzip.NewWriter(&buf)
+	z.Write(raw.Bytes())
+	z.Close()
+	return &buf
+}
+
+func TestReadNBT(t *testing.T) {
+
+	root := map[string]interface{} {
+		"byte": int8(-3), "short": int16(300), "int": int32(-70000), "long": int64(1) << 40,
+		"float": float32(1.5), "double": 2.25, "bytes": []byte { 1, 2, 3 }, "string": "stone",
+		"ints": []int32 { 1, -2 }, "longs": []int64 { 3 },
+		"list": []interface{} { int16(1), int16(2) },
+		"nested": map[string]interface{} { "empty": []interface{}(nil) },
+	}
+
+	for _, compress := range []bool { false, true } {
+		name, got, err := ReadNBT(nbtFile("root", root, compress))
+		if err != nil { t.Fatal(err) }
+		if name != "root" || !reflect.DeepEqual(got, root) {
+			t.Errorf("gzip %v: %q %v expected, was %q %v", compress, "root", root, name, got)
+		}
+	}
+
+	full := nbtFile("", root, false).Bytes()
+	for _, bad := range [][]byte { {}, { TagInt, 0, 0 }, full[:len(full)-1], { TagCompound, 0, 0, 99 },
+		{ TagCompound, 0, 0, TagByteArray, 0, 1, 'a', 0x7f, 0xff, 0xff, 0xff } } {
+		if _, _, err := ReadNBT(bytes.NewReader(bad)); err == nil {
+			t.Errorf("% x: error expected", bad)
+		}
+	}
+}
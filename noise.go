@@ -0,0 +1,133 @@
+package glvox
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Noise is seeded 3D gradient noise. The same seed always yields the same
+// values.
+type Noise struct {
+	perm [512]uint8
+}
+
+func NewNoise(seed int64) *Noise {
+	n := new(Noise)
+	for i, p := range rand.New(rand.NewSource(seed)).Perm(256) {
+		n.perm[i] = uint8(p)
+		n.perm[i+256] = uint8(p)
+	}
+	return n
+}
+
+// the twelve cube edge directions, as in improved Perlin noise
+var noiseGrad = [12][3]float64 {
+	{ 1, 1, 0 }, { -1, 1, 0 }, { 1, -1, 0 }, { -1, -1, 0 },
+	{ 1, 0, 1 }, { -1, 0, 1 }, { 1, 0, -1 }, { -1, 0, -1 },
+	{ 0, 1, 1 }, { 0, -1, 1 }, { 0, 1, -1 }, { 0, -1, -1 },
+}
+
+func (n *Noise) hash(i, j, k int) int {
+	return int(n.perm[int(n.perm[int(n.perm[i&255]) + j&255]) + k&255])
+}
+
+func (n *Noise) dot(h int, x, y, z float64) float64 {
+	g := noiseGrad[h % 12]
+	return g[0]*x + g[1]*y + g[2]*z
+}
+
+// Gradient returns improved Perlin noise at (x, y, z), roughly in [-1, 1]
+// and 0 at integer coordinates.
+func (n *Noise) Gradient(x, y, z float64) float64 {
+
+	fx, fy, fz := math.Floor(x), math.Floor(y), math.Floor(z)
+	i, j, k := int(fx), int(fy), int(fz)
+	x, y, z = x - fx, y - fy, z - fz
+
+	fade := func(t float64) float64 { return t*t*t*(t*(t*6 - 15) + 10) }
+	lerp := func(t, a, b float64) float64 { return a + t*(b - a) }
+	u, v, w := fade(x), fade(y), fade(z)
+
+	g := func(di, dj, dk int) float64 {
+		return n.dot(n.hash(i+di, j+dj, k+dk), x - float64(di), y - float64(dj), z - float64(dk))
+	}
+
+	return lerp(w,
+		lerp(v, lerp(u, g(0, 0, 0), g(1, 0, 0)), lerp(u, g(0, 1, 0), g(1, 1, 0))),
+		lerp(v, lerp(u, g(0, 0, 1), g(1, 0, 1)), lerp(u, g(0, 1, 1), g(1, 1, 1))))
+}
+
+// Simplex returns 3D simplex noise at (x, y, z), in [-1, 1].
+func (n *Noise) Simplex(x, y, z float64) float64 {
+
+	const f3, g3 = 1.0 / 3.0, 1.0 / 6.0
+
+	s := (x + y + z) * f3
+	i, j, k := int(math.Floor(x + s)), int(math.Floor(y + s)), int(math.Floor(z + s))
+	t := float64(i + j + k) * g3
+	x0, y0, z0 := x - (float64(i) - t), y - (float64(j) - t), z - (float64(k) - t)
+
+	// which of the six simplices of the skewed cube
+	var i1, j1, k1, i2, j2, k2 int
+	switch {
+	case x0 >= y0 && y0 >= z0: i1, i2, j2 = 1, 1, 1
+	case x0 >= y0 && x0 >= z0: i1, i2, k2 = 1, 1, 1
+	case x0 >= y0: k1, i2, k2 = 1, 1, 1
+	case y0 < z0: k1, j2, k2 = 1, 1, 1
+	case x0 < z0: j1, j2, k2 = 1, 1, 1
+	default: j1, i2, j2 = 1, 1, 1
+	}
+
+	corner := func(di, dj, dk int, x, y, z float64) float64 {
+		t := .6 - x*x - y*y - z*z
+		if t < 0 { return 0 }
+		t *= t
+		return t * t * n.dot(n.hash(i+di, j+dj, k+dk), x, y, z)
+	}
+
+	return 32 * (corner(0, 0, 0, x0, y0, z0) +
+		corner(i1, j1, k1, x0 - float64(i1) + g3, y0 - float64(j1) + g3, z0 - float64(k1) + g3) +
+		corner(i2, j2, k2, x0 - float64(i2) + 2*g3, y0 - float64(j2) + 2*g3, z0 - float64(k2) + 2*g3) +
+		corner(1, 1, 1, x0 - 1 + 3*g3, y0 - 1 + 3*g3, z0 - 1 + 3*g3))
+}
+
+// Fractal sums Octaves layers of noise, each scaled in frequency by
+// Lacunarity and in amplitude by Gain.
+type Fractal struct {
+	Octaves int
+	Lacunarity, Gain float64
+}
+
+// FBM is fractal brownian motion of noise, normalized to about [-1, 1].
+func (fr Fractal) FBM(noise func(x, y, z float64) float64, x, y, z float64) float64 {
+	return fr.sum(x, y, z, noise)
+}
+
+// Turbulence sums the absolute values of noise, in about [0, 1].
+func (fr Fractal) Turbulence(noise func(x, y, z float64) float64, x, y, z float64) float64 {
+	return fr.sum(x, y, z, func(x, y, z float64) float64 {
+		return math.Abs(noise(x, y, z))
+	})
+}
+
+// Ridged folds noise into sharp ridges, in about [0, 1].
+func (fr Fractal) Ridged(noise func(x, y, z float64) float64, x, y, z float64) float64 {
+	return fr.sum(x, y, z, func(x, y, z float64) float64 {
+		r := 1 - math.Abs(noise(x, y, z))
+		return r * r
+	})
+}
+
+func (fr Fractal) sum(x, y, z float64, f func(x, y, z float64) float64) float64 {
+
+	sum, amp, norm := 0.0, 1.0, 0.0
+	for o := 0; o < fr.Octaves; o++ {
+		sum += amp * f(x, y, z)
+		norm += amp
+		amp *= fr.Gain
+		x, y, z = x*fr.Lacunarity, y*fr.Lacunarity, z*fr.Lacunarity
+	}
+
+	if norm == 0 { return 0 }
+	return sum / norm
+}
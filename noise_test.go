@@ -0,0 +1,105 @@
+package glvox
+
+import (
+	"testing"
+)
+
+func TestNoise(t *testing.T) {
+
+	a, b, c := NewNoise(1), NewNoise(1), NewNoise(2)
+
+	same, differ := true, false
+	lo, hi := 0.0, 0.0
+	for i := 0; i < 1000; i++ {
+		x, y, z := float64(i) * .137, float64(i) * .071, float64(i) * -.053
+		for _, f := range []func(n *Noise) float64 {
+			func(n *Noise) float64 { return n.Gradient(x, y, z) },
+			func(n *Noise) float64 { return n.Simplex(x, y, z) },
+		} {
+			v := f(a)
+			if v != f(b) { same = false }
+			if v != f(c) { differ = true }
+			if v < lo { lo = v }; if v > hi { hi = v }
+		}
+	}
+
+	if !same { t.Error("same seed must yield the same noise") }
+	if !differ { t.Error("different seeds must yield different noise") }
+	if lo < -1.1 || hi > 1.1 || lo > -.3 || hi < .3 {
+		t.Errorf("noise range [%f, %f] out of [-1, 1] or too narrow", lo, hi)
+	}
+
+	if v := a.Gradient(3, -4, 5); v != 0 {
+		t.Errorf("gradient noise 0 at lattice points expected, was %f", v)
+	}
+
+	// continuity
+	if d := a.Simplex(.5, .5, .5) - a.Simplex(.5001, .5, .5); d > .01 || d < -.01 {
+		t.Errorf("simplex noise jumps by %f", d)
+	}
+
+	fr := Fractal { 4, 2, .5 }
+	for i := 0; i < 100; i++ {
+		x := float64(i) * .31
+		if v := fr.Ridged(a.Simplex, x, 0, 0); v < 0 || v > 1 {
+			t.Errorf("ridged noise %f out of [0, 1]", v)
+		}
+		if v := fr.Turbulence(a.Gradient, x, 0, 0); v < 0 || v > 1 {
+			t.Errorf("turbulence %f out of [0, 1]", v)
+		}
+	}
+}
+
+func TestTerrain(t *testing.T) {
+
+	tr := &Terrain {
+		Seed: 7, Height: 20, Amplitude: 8, Scale: 24,
+		Fractal: Fractal { 3, 2, .5 },
+		Layers: []Layer { { 1, 1 }, { 3, 2 }, { 0, 3 } },
+	}
+
+	oct := NewOctree(64)
+	tr.Generate(oct, Size { 64, 64, 64 })
+
+	for z := 0; z < 64; z += 7 {
+		for x := 0; x < 64; x += 5 {
+			top := -1
+			for y := 63; y >= 0; y-- {
+				if v, _ := oct.Get(x, y, z); v != 0 { top = y; break }
+			}
+			if top < 11 || top > 29 {
+				t.Fatalf("(%d, %d): surface at %d out of range", x, z, top)
+			}
+
+			exp := []int { 1, 2, 2, 2, 3, 3 }
+			for d, e := range exp {
+				if v, _ := oct.Get(x, top-d, z); v != e {
+					t.Errorf("(%d, %d): layer %d expected at depth %d, was %d", x, z, e, d, v)
+				}
+			}
+		}
+	}
+
+	solid := count(OctreeVolume { oct })
+	tr.CaveScale, tr.CaveWidth = 16, .15
+	oct = NewOctree(64)
+	tr.Generate(oct, Size { 64, 64, 64 })
+	if n := count(OctreeVolume { oct }); n >= solid || n < solid*3/4 {
+		t.Errorf("caves expected to remove some voxels: %d of %d left", n, solid)
+	}
+
+	// zero scales give a flat surface without caves, and a Depth of 0
+	// reaches the bottom wherever it is
+	flat := &Terrain { Height: 10, Amplitude: 8, CaveWidth: .15,
+		Layers: []Layer { { 2, 1 }, { 0, 2 }, { 3, 3 } } }
+	oct = NewOctree(16)
+	flat.Generate(oct, Size { 16, 16, 16 })
+	if n := count(OctreeVolume { oct }); n != 16*16*10 {
+		t.Errorf("flat terrain of %d voxels expected, was %d", 16*16*10, n)
+	}
+	for y, e := range []int { 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 0 } {
+		if v, _ := oct.Get(3, y, 5); v != e {
+			t.Errorf("layer %d expected at height %d, was %d", e, y, v)
+		}
+	}
+}
@@ -27,6 +27,7 @@ func NewOctree(size int) *Octree {
 
 func (oct *Octree) Trace(ro, rd Vec3) (pos Vec3, hit bool) {
 
+	pos = ro
 	var s Vec3
 	s.X = float32(1.0); if rd.X < 0 { s.X = -1.0 }
 	s.Y = float32(1.0); if rd.Y < 0 { s.Y = -1.0 }
@@ -59,9 +60,15 @@ func (oct *Octree) Voxel(pos, dir Vec3) Vox {
 
 	val, size := oct.Get(x, y, z)
 
+	// the lowest corner of the node, outside the octree of its size
+	align := func(c int) float32 {
+		if c < 0 { c -= size-1 }
+		return float32(c / size * size)
+	}
+
 	s := float32(size) / 2.0
 	center :=
-		Vec3{float32(x), float32(y), float32(z)}.Plus(
+		Vec3{align(x), align(y), align(z)}.Plus(
 		Vec3{s, s, s})
 	dist := pos.Minus(center)
 	v := Vox { dist, s, float32(val) }
@@ -157,3 +164,124 @@ func (oct *Octree) String() string {
 	return s
 }
 
+
+type leaf struct {
+	x, y, z, size, val int
+}
+
+// leaves calls fn for every leaf intersecting the box [x0,x1)×[y0,y1)×[z0,z1).
+// The tree is walked with an explicit stack, so depth is not limited.
+func (oct *Octree) leaves(x0, y0, z0, x1, y1, z1 int, fn func(l leaf)) {
+
+	if oct.Size < 2 {
+		fn(leaf { 0, 0, 0, oct.Size, 0 })
+		return
+	}
+
+	type node struct { i, x, y, z, size int }
+	stack := []node { { 0, 0, 0, 0, oct.Size } }
+
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		s := n.size >> 1
+		for off := 0; off < 8; off++ {
+			x := n.x + (off & 1) * s
+			y := n.y + (off >> 1 & 1) * s
+			z := n.z + (off >> 2) * s
+			if x >= x1 || x+s <= x0 || y >= y1 || y+s <= y0 || z >= z1 || z+s <= z0 {
+				continue
+			}
+
+			idx := oct.Index[n.i<<3 + off]
+			if idx <= 0 {
+				fn(leaf { x, y, z, s, -idx })
+			} else {
+				stack = append(stack, node { idx, x, y, z, s })
+			}
+		}
+	}
+}
+
+// setNode sets the aligned cube of edge length size at (x, y, z) to v,
+// replacing whatever subtree was there.
+func (oct *Octree) setNode(x, y, z, size int, v int) {
+
+	s := oct.Size
+	if size >= s {
+		oct.Index = append(oct.Index[:0], -v, -v, -v, -v,  -v, -v, -v, -v)
+		return
+	}
+
+	i := 0
+	for {
+		s >>= 1
+		off := 0
+
+		if z >= s { off += 4; z -= s }
+		if y >= s { off += 2; y -= s }
+		if x >= s { off += 1; x -= s }
+
+		if s <= size {
+			oct.Index[i<<3 + off] = -v
+			return
+		}
+
+		idx := oct.Index[i<<3 + off]
+		if idx <= 0 {
+			if -idx == v { return }
+			idx = oct.newIndex(idx)
+			oct.Index[i<<3 + off] = idx
+		}
+
+		i = idx
+	}
+}
+
+// SetBox sets the box [x0,x1)×[y0,y1)×[z0,z1) to v. Nodes inside the box
+// become single leaves and leaves already holding v are not descended.
+func (oct *Octree) SetBox(x0, y0, z0, x1, y1, z1 int, v int) {
+
+	if x0 < 0 { x0 = 0 }; if x1 > oct.Size { x1 = oct.Size }
+	if y0 < 0 { y0 = 0 }; if y1 > oct.Size { y1 = oct.Size }
+	if z0 < 0 { z0 = 0 }; if z1 > oct.Size { z1 = oct.Size }
+	if x0 >= x1 || y0 >= y1 || z0 >= z1 { return }
+
+	if x0 == 0 && y0 == 0 && z0 == 0 && x1 == oct.Size && y1 == oct.Size && z1 == oct.Size {
+		oct.setNode(0, 0, 0, oct.Size, v)
+		return
+	}
+
+	type node struct { i, x, y, z, size int }
+	stack := []node { { 0, 0, 0, 0, oct.Size } }
+
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		s := n.size >> 1
+		for off := 0; off < 8; off++ {
+			x := n.x + (off & 1) * s
+			y := n.y + (off >> 1 & 1) * s
+			z := n.z + (off >> 2) * s
+			if x >= x1 || x+s <= x0 || y >= y1 || y+s <= y0 || z >= z1 || z+s <= z0 {
+				continue
+			}
+
+			k := n.i<<3 + off
+			if x >= x0 && x+s <= x1 && y >= y0 && y+s <= y1 && z >= z0 && z+s <= z1 {
+				oct.Index[k] = -v
+				continue
+			}
+
+			idx := oct.Index[k]
+			if idx <= 0 {
+				if -idx == v { continue }
+				idx = oct.newIndex(idx)
+				oct.Index[k] = idx
+			}
+			stack = append(stack, node { idx, x, y, z, s })
+		}
+	}
+}
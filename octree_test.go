@@ -7,12 +7,12 @@ import (
 
 func buildOctree() *Octree {
 
-	s, s1 := int32(16), int32(15)
+	s, s1 := 16, 15
 
 	oct := NewOctree(s)
-	for z := int32(0); z < s; z++ {
-		for y := int32(0); y < s; y++ {
-			for x := int32(0); x < s; x++ {
+	for z := 0; z < s; z++ {
+		for y := 0; y < s; y++ {
+			for x := 0; x < s; x++ {
 				oct.Set(x, y, z, 0)
 
 				if x == 0 && y == 0 && z == 0 {
@@ -101,8 +101,8 @@ func TestOctreeGetAll(t *testing.T) {
 	for z := int32(0); z < grid.D; z++ {
 		for y := int32(0); y < grid.D; y++ {
 			for x := int32(0); x < grid.D; x++ {
-				expected := grid.Get(x, y, z)
-				actual, _ := oct.Get(x, y, z)
+				expected := int(grid.Get(x, y, z))
+				actual, _ := oct.Get(int(x), int(y), int(z))
 				if expected != actual {
 					t.Errorf("(%d, %d, %d): expected %d, was %d",
 						x, y, z, expected, actual)
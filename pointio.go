@@ -0,0 +1,223 @@
+package glvox
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ReadXYZ reads an ASCII point list, one "x y z" or "x y z r g b" point
+// per line. Colours are 0..255; they are kept if the first point has one.
+// Empty lines and lines starting with # are skipped.
+func ReadXYZ(r io.Reader) (pc *PointCloud, err error) {
+
+	pc = new(PointCloud)
+	scan := bufio.NewScanner(r)
+	colors := -1
+
+	for line := 1; scan.Scan(); line++ {
+		fields := strings.Fields(scan.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") { continue }
+
+		if colors < 0 {
+			colors = 0
+			if len(fields) >= 6 { colors = 1 }
+		}
+		if len(fields) < 3 || colors == 1 && len(fields) < 6 {
+			return nil, fmt.Errorf("xyz line %d: too few fields", line)
+		}
+
+		var f [3]float64
+		for i := range f {
+			if f[i], err = strconv.ParseFloat(fields[i], 32); err != nil {
+				return nil, fmt.Errorf("xyz line %d: %v", line, err)
+			}
+		}
+		pc.Points = append(pc.Points, Vec3 { float32(f[0]), float32(f[1]), float32(f[2]) })
+
+		if colors == 1 {
+			var c [3]uint8
+			for i := range c {
+				n, err := strconv.ParseUint(fields[3+i], 10, 8)
+				if err != nil { return nil, fmt.Errorf("xyz line %d: %v", line, err) }
+				c[i] = uint8(n)
+			}
+			pc.Colors = append(pc.Colors, c)
+		}
+	}
+
+	return pc, scan.Err()
+}
+
+type plyProperty struct {
+	name, typ string
+	count string		// type of the length of list properties
+}
+
+type plyElement struct {
+	name string
+	count int
+	props []plyProperty
+}
+
+var plySizes = map[string]int {
+	"char": 1, "uchar": 1, "int8": 1, "uint8": 1,
+	"short": 2, "ushort": 2, "int16": 2, "uint16": 2,
+	"int": 4, "uint": 4, "int32": 4, "uint32": 4, "float": 4, "float32": 4,
+	"double": 8, "float64": 8,
+}
+
+// ReadPLY reads the vertex element of an ASCII or binary little endian PLY
+// file: properties x, y, z and, if present, red, green and blue. Other
+// elements and properties are skipped.
+func ReadPLY(r io.Reader) (pc *PointCloud, err error) {
+
+	buf := bufio.NewReader(r)
+
+	line, err := buf.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		return nil, errors.New("not a ply file")
+	}
+
+	format := ""
+	var elems []*plyElement
+	for {
+		line, err = buf.ReadString('\n')
+		if err != nil { return nil, errors.New("ply: header without end_header") }
+
+		f := strings.Fields(line)
+		if len(f) == 0 { continue }
+
+		switch f[0] {
+		case "format":
+			if len(f) < 2 { return nil, errors.New("ply: bad format line") }
+			format = f[1]
+		case "element":
+			if len(f) < 3 { return nil, errors.New("ply: bad element line") }
+			n, err := strconv.Atoi(f[2])
+			if err != nil || n < 0 { return nil, fmt.Errorf("ply: bad element count %q", f[2]) }
+			elems = append(elems, &plyElement { name: f[1], count: n })
+		case "property":
+			if len(elems) == 0 { return nil, errors.New("ply: property outside element") }
+			e := elems[len(elems)-1]
+			switch {
+			case len(f) == 5 && f[1] == "list":
+				if plySizes[f[2]] == 0 || plySizes[f[3]] == 0 {
+					return nil, fmt.Errorf("ply: bad property %q", strings.TrimSpace(line))
+				}
+				e.props = append(e.props, plyProperty { f[4], f[3], f[2] })
+			case len(f) == 3 && plySizes[f[1]] > 0:
+				e.props = append(e.props, plyProperty { f[2], f[1], "" })
+			default:
+				return nil, fmt.Errorf("ply: bad property %q", strings.TrimSpace(line))
+			}
+		}
+
+		if f[0] == "end_header" { break }
+	}
+
+	if format != "ascii" && format != "binary_little_endian" {
+		return nil, fmt.Errorf("ply: unsupported format %q", format)
+	}
+
+	pc = new(PointCloud)
+	for _, e := range elems {
+		idx := map[string]int { "x": -1, "y": -1, "z": -1, "red": -1, "green": -1, "blue": -1 }
+		for i, p := range e.props {
+			if _, ok := idx[p.name]; ok && p.count == "" { idx[p.name] = i }
+		}
+
+		vertex := e.name == "vertex"
+		if vertex && (idx["x"] < 0 || idx["y"] < 0 || idx["z"] < 0) {
+			return nil, errors.New("ply: vertex without x, y, z")
+		}
+		color := vertex && idx["red"] >= 0 && idx["green"] >= 0 && idx["blue"] >= 0
+
+		vals := make([]float64, len(e.props))
+		for n := 0; n < e.count; n++ {
+			if format == "ascii" {
+				err = plyReadASCII(buf, e, vals)
+			} else {
+				err = plyReadBinary(buf, e, vals)
+			}
+			if err != nil { return nil, fmt.Errorf("ply: %s %d: %v", e.name, n, err) }
+			if !vertex { continue }
+
+			pc.Points = append(pc.Points, Vec3 {
+				float32(vals[idx["x"]]), float32(vals[idx["y"]]), float32(vals[idx["z"]]) })
+			if color {
+				pc.Colors = append(pc.Colors, [3]uint8 {
+					uint8(vals[idx["red"]]), uint8(vals[idx["green"]]), uint8(vals[idx["blue"]]) })
+			}
+		}
+
+		if vertex { return pc, nil }
+	}
+
+	return nil, errors.New("ply: no vertex element")
+}
+
+func plyReadASCII(buf *bufio.Reader, e *plyElement, vals []float64) error {
+
+	line, err := buf.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") { return err }
+
+	f := strings.Fields(line)
+	i := 0
+	for k, p := range e.props {
+		if i >= len(f) { return errors.New("too few values") }
+		if p.count != "" {
+			n, err := strconv.Atoi(f[i])
+			if err != nil { return err }
+			if n < 0 || n > len(f) - i - 1 { return fmt.Errorf("bad list count %d", n) }
+			i += n + 1
+			continue
+		}
+		if vals[k], err = strconv.ParseFloat(f[i], 64); err != nil { return err }
+		i++
+	}
+
+	return nil
+}
+
+func plyReadBinary(buf *bufio.Reader, e *plyElement, vals []float64) error {
+
+	var b [8]byte
+	read := func(typ string) (float64, error) {
+		n := plySizes[typ]
+		if _, err := io.ReadFull(buf, b[:n]); err != nil { return 0, err }
+		le := binary.LittleEndian
+		switch typ {
+		case "char", "int8": return float64(int8(b[0])), nil
+		case "uchar", "uint8": return float64(b[0]), nil
+		case "short", "int16": return float64(int16(le.Uint16(b[:]))), nil
+		case "ushort", "uint16": return float64(le.Uint16(b[:])), nil
+		case "int", "int32": return float64(int32(le.Uint32(b[:]))), nil
+		case "uint", "uint32": return float64(le.Uint32(b[:])), nil
+		case "float", "float32": return float64(math.Float32frombits(le.Uint32(b[:]))), nil
+		}
+		return math.Float64frombits(le.Uint64(b[:])), nil
+	}
+
+	for k, p := range e.props {
+		if p.count == "" {
+			v, err := read(p.typ)
+			if err != nil { return err }
+			vals[k] = v
+			continue
+		}
+
+		n, err := read(p.count)
+		if err != nil { return err }
+		for i := 0; i < int(n); i++ {
+			if _, err := read(p.typ); err != nil { return err }
+		}
+	}
+
+	return nil
+}
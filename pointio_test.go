@@ -0,0 +1,75 @@
+package glvox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestReadXYZ(t *testing.T) {
+
+	pc, err := ReadXYZ(strings.NewReader("# scan\n1 2 3 255 0 10\n\n4.5 5 -6 1 2 3\n"))
+	if err != nil { t.Fatal(err) }
+
+	if len(pc.Points) != 2 || pc.Points[1] != (Vec3 { 4.5, 5, -6 }) {
+		t.Errorf("unexpected points %v", pc.Points)
+	}
+	if len(pc.Colors) != 2 || pc.Colors[0] != [3]uint8 { 255, 0, 10 } {
+		t.Errorf("unexpected colours %v", pc.Colors)
+	}
+
+	if _, err := ReadXYZ(strings.NewReader("1 2 3 4 5 6\n1 2 3\n")); err == nil {
+		t.Error("error expected for a point without colour")
+	}
+}
+
+func TestReadPLY(t *testing.T) {
+
+	ascii := "ply\nformat ascii 1.0\ncomment test\nelement vertex 2\n" +
+		"property float x\nproperty float y\nproperty float z\n" +
+		"property uchar red\nproperty uchar green\nproperty uchar blue\n" +
+		"element face 1\nproperty list uchar int vertex_indices\nend_header\n" +
+		"0 0 0 10 20 30\n1 2 3 40 50 60\n3 0 1 1\n"
+
+	pc, err := ReadPLY(strings.NewReader(ascii))
+	if err != nil { t.Fatal(err) }
+	if len(pc.Points) != 2 || pc.Points[1] != (Vec3 { 1, 2, 3 }) || pc.Colors[1] != [3]uint8 { 40, 50, 60 } {
+		t.Errorf("unexpected cloud %v %v", pc.Points, pc.Colors)
+	}
+
+	// binary, with a list element in front and a double property
+	var b bytes.Buffer
+	b.WriteString("ply\nformat binary_little_endian 1.0\n" +
+		"element junk 1\nproperty list uchar short idx\n" +
+		"element vertex 2\nproperty double x\nproperty float y\nproperty float z\nproperty int flags\n" +
+		"end_header\n")
+	le := binary.LittleEndian
+	binary.Write(&b, le, uint8(2)); binary.Write(&b, le, []int16 { 7, 8 })
+	for _, p := range []Vec3 { { 1, 2, 3 }, { -1, .5, 9 } } {
+		binary.Write(&b, le, float64(p.X))
+		binary.Write(&b, le, []float32 { p.Y, p.Z })
+		binary.Write(&b, le, int32(-1))
+	}
+
+	pc, err = ReadPLY(&b)
+	if err != nil { t.Fatal(err) }
+	if len(pc.Points) != 2 || pc.Points[1] != (Vec3 { -1, .5, 9 }) || pc.Colors != nil {
+		t.Errorf("unexpected cloud %v %v", pc.Points, pc.Colors)
+	}
+
+	// truncated
+	if _, err := ReadPLY(strings.NewReader(ascii[:strings.Index(ascii, "1 2 3")])); err == nil {
+		t.Error("error expected for missing vertices")
+	}
+
+	// list counts that are negative or run past the line, in front of
+	// the vertices
+	head := "ply\nformat ascii 1.0\nelement face 1\nproperty list uchar int vertex_indices\nproperty uchar flags\n" +
+		"element vertex 1\nproperty float x\nproperty float y\nproperty float z\nend_header\n"
+	for _, face := range []string { "-5 1 2 3\n", "4 0 1 1\n" } {
+		if _, err := ReadPLY(strings.NewReader(head + face + "0 0 0\n")); err == nil {
+			t.Errorf("%q: error expected for a bad list count", face)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package glvox
+
+import (
+	"math"
+)
+
+// PointCloud holds scanned points; Colors is nil or parallel to Points.
+type PointCloud struct {
+	Points []Vec3
+	Colors [][3]uint8
+}
+
+// PointBin collects the points falling into one voxel.
+type PointBin struct {
+	Count int
+	Color [3]uint8		// average colour
+}
+
+// BinPoints sorts the points of pc into voxels of edge length voxel, voxel
+// (x, y, z) covering org + [x, x+1)*voxel and so on.
+func BinPoints(pc *PointCloud, org Vec3, voxel float32) map[[3]int]*PointBin {
+
+	type sum struct { n int; r, g, b int }
+	sums := make(map[[3]int]*sum)
+
+	for i, p := range pc.Points {
+		q := p.Minus(org).Mul(1 / voxel)
+		k := [3]int {
+			int(math.Floor(float64(q.X))),
+			int(math.Floor(float64(q.Y))),
+			int(math.Floor(float64(q.Z))),
+		}
+
+		s := sums[k]
+		if s == nil { s = new(sum); sums[k] = s }
+		s.n++
+		if pc.Colors != nil {
+			c := pc.Colors[i]
+			s.r += int(c[0]); s.g += int(c[1]); s.b += int(c[2])
+		}
+	}
+
+	bins := make(map[[3]int]*PointBin, len(sums))
+	for k, s := range sums {
+		b := &PointBin { Count: s.n }
+		if pc.Colors != nil {
+			b.Color = [3]uint8 { uint8((s.r + s.n/2) / s.n), uint8((s.g + s.n/2) / s.n), uint8((s.b + s.n/2) / s.n) }
+		}
+		bins[k] = b
+	}
+
+	return bins
+}
+
+// VoxelizePoints bins pc like BinPoints and sets every voxel hit by at
+// least minCount points to value(bin), or to the hit count if value is
+// nil. PackRGB turns the average colour into a value. The bins are
+// returned for further use.
+func VoxelizePoints(pc *PointCloud, dst Setter, org Vec3, voxel float32,
+	minCount int, value func(b *PointBin) int) map[[3]int]*PointBin {
+
+	bins := BinPoints(pc, org, voxel)
+	for k, b := range bins {
+		if b.Count < minCount { continue }
+
+		v := b.Count
+		if value != nil { v = value(b) }
+		dst.Set(k[0], k[1], k[2], v)
+	}
+
+	return bins
+}
+
+// PackRGB packs a colour into a non-zero voxel value, 0x1rrggbb.
+func PackRGB(c [3]uint8) int {
+	return 1<<24 | int(c[0])<<16 | int(c[1])<<8 | int(c[2])
+}
+
+// UnpackRGB returns the colour of a value made by PackRGB.
+func UnpackRGB(v int) [3]uint8 {
+	return [3]uint8 { uint8(v >> 16), uint8(v >> 8), uint8(v) }
+}
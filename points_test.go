@@ -0,0 +1,35 @@
+package glvox
+
+import (
+	"testing"
+)
+
+func TestVoxelizePoints(t *testing.T) {
+
+	pc := &PointCloud {
+		Points: []Vec3 { { .1, .1, .1 }, { .4, .2, .3 }, { .3, .3, .3 }, { 1.2, .1, .1 }, { -.1, 0, 0 } },
+		Colors: [][3]uint8 { { 0, 0, 0 }, { 100, 50, 0 }, { 200, 100, 255 }, { 9, 9, 9 }, { 1, 1, 1 } },
+	}
+
+	oct := NewOctree(8)
+	bins := VoxelizePoints(pc, oct, Vec3 {}, .5, 2, nil)
+	if len(bins) != 3 {
+		t.Errorf("3 bins expected, was %d", len(bins))
+	}
+	if v, _ := oct.Get(0, 0, 0); v != 3 {
+		t.Errorf("hit count 3 expected, was %d", v)
+	}
+	if v, _ := oct.Get(2, 0, 0); v != 0 {
+		t.Errorf("single hit filtered expected, was %d", v)
+	}
+
+	oct = NewOctree(8)
+	VoxelizePoints(pc, oct, Vec3 {}, .5, 1, func(b *PointBin) int { return PackRGB(b.Color) })
+	v, _ := oct.Get(0, 0, 0)
+	if c := UnpackRGB(v); c != [3]uint8 { 100, 50, 85 } {
+		t.Errorf("average colour expected, was %v", c)
+	}
+	if v, _ := oct.Get(2, 0, 0); v != PackRGB([3]uint8 { 9, 9, 9 }) {
+		t.Errorf("colour value expected, was %x", v)
+	}
+}
@@ -0,0 +1,125 @@
+package glvox
+
+import (
+	"math"
+)
+
+// qef accumulates the planes (p, n) of hermite samples; its minimizer is
+// the point closest to all of them in the least squares sense.
+type qef struct {
+	ata [6]float64		// xx, xy, xz, yy, yz, zz
+	atb [3]float64
+	btb float64
+	mass [3]float64
+	n int
+}
+
+func (q *qef) add(p, n Vec3) {
+	nx, ny, nz := float64(n.X), float64(n.Y), float64(n.Z)
+	b := float64(n.Dot(p))
+
+	q.ata[0] += nx*nx; q.ata[1] += nx*ny; q.ata[2] += nx*nz
+	q.ata[3] += ny*ny; q.ata[4] += ny*nz; q.ata[5] += nz*nz
+	q.atb[0] += nx*b; q.atb[1] += ny*b; q.atb[2] += nz*b
+	q.btb += b*b
+
+	q.mass[0] += float64(p.X); q.mass[1] += float64(p.Y); q.mass[2] += float64(p.Z)
+	q.n++
+}
+
+func (q *qef) merge(o *qef) {
+	for i := range q.ata { q.ata[i] += o.ata[i] }
+	for i := range q.atb { q.atb[i] += o.atb[i]; q.mass[i] += o.mass[i] }
+	q.btb += o.btb
+	q.n += o.n
+}
+
+// solve returns the minimizer and its squared error. Directions the
+// samples do not constrain stay at the mass point, which keeps flat and
+// edge regions from drifting. Solutions outside [lo, hi] fall back to the
+// mass point.
+func (q *qef) solve(lo, hi Vec3) (x Vec3, err float64) {
+
+	if q.n == 0 { return lo.Plus(hi).Mul(.5), 0 }
+
+	m := [3]float64 { q.mass[0] / float64(q.n), q.mass[1] / float64(q.n), q.mass[2] / float64(q.n) }
+	a := [3][3]float64 {
+		{ q.ata[0], q.ata[1], q.ata[2] },
+		{ q.ata[1], q.ata[3], q.ata[4] },
+		{ q.ata[2], q.ata[4], q.ata[5] },
+	}
+
+	// right hand side relative to the mass point
+	var r [3]float64
+	for i := 0; i < 3; i++ {
+		r[i] = q.atb[i] - (a[i][0]*m[0] + a[i][1]*m[1] + a[i][2]*m[2])
+	}
+
+	val, vec := eigen3(a)
+	max := math.Max(val[0], math.Max(val[1], val[2]))
+
+	var s [3]float64
+	copy(s[:], m[:])
+	for k := 0; k < 3; k++ {
+		if val[k] < .1 * max || val[k] <= 0 { continue }
+		d := (vec[0][k]*r[0] + vec[1][k]*r[1] + vec[2][k]*r[2]) / val[k]
+		for i := 0; i < 3; i++ { s[i] += vec[i][k] * d }
+	}
+
+	x = Vec3 { float32(s[0]), float32(s[1]), float32(s[2]) }
+	if x.Clamp(lo, hi) != x {
+		x = Vec3 { float32(m[0]), float32(m[1]), float32(m[2]) }
+		s = m
+	}
+
+	err = q.btb - 2*(s[0]*q.atb[0] + s[1]*q.atb[1] + s[2]*q.atb[2])
+	for i := 0; i < 3; i++ {
+		err += s[i] * (a[i][0]*s[0] + a[i][1]*s[1] + a[i][2]*s[2])
+	}
+	if err < 0 { err = 0 }
+
+	return
+}
+
+// eigen3 diagonalizes the symmetric matrix a by Jacobi rotations. The
+// columns of vec are the eigenvectors.
+func eigen3(a [3][3]float64) (val [3]float64, vec [3][3]float64) {
+
+	vec = [3][3]float64 { { 1, 0, 0 }, { 0, 1, 0 }, { 0, 0, 1 } }
+
+	for sweep := 0; sweep < 32; sweep++ {
+		off := a[0][1]*a[0][1] + a[0][2]*a[0][2] + a[1][2]*a[1][2]
+		if off < 1e-24 { break }
+
+		for p := 0; p < 2; p++ {
+			for q := p+1; q < 3; q++ {
+				if a[p][q] == 0 { continue }
+
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta + 1))
+				if theta < 0 { t = -t }
+				c := 1 / math.Sqrt(t*t + 1)
+				s := t * c
+
+				for k := 0; k < 3; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < 3; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < 3; k++ {
+					vkp, vkq := vec[k][p], vec[k][q]
+					vec[k][p] = c*vkp - s*vkq
+					vec[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	val = [3]float64 { a[0][0], a[1][1], a[2][2] }
+	return
+}
@@ -0,0 +1,137 @@
+package glvox
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// QBMatrix is a named matrix of a Qubicle file, Pos being the cell of its
+// lowest corner in glvox axes.
+type QBMatrix struct {
+	Name string
+	Pos [3]int
+	Size Size
+}
+
+// codes of compressed slices, CODEFLAG and NEXTSLICEFLAG of the spec
+const (
+	qbRepeat = 2
+	qbNextSlice = 6
+)
+
+// ReadQB reads the matrices of a Qubicle .qb file into dst, each at its
+// position plus (offx, offy, offz). Voxels are set to value(colour), or
+// PackRGB of it if value is nil. Left-handed files are mirrored along z.
+// The matrices are returned for bounds and names.
+func ReadQB(r io.Reader, dst Setter, value func(c color.RGBA) int,
+	offx, offy, offz int) (ms []QBMatrix, err error) {
+
+	buf := bufio.NewReader(r)
+	le := binary.LittleEndian
+
+	var head [6]uint32
+	if err = binary.Read(buf, le, &head); err != nil || head[0] != 0x00000101 {
+		return nil, errors.New("not a qb file")
+	}
+	bgra, left, compressed, masked := head[1] == 1, head[2] == 0, head[3] == 1, head[4] == 1
+
+	if value == nil {
+		value = func(c color.RGBA) int { return PackRGB([3]uint8 { c.R, c.G, c.B }) }
+	}
+
+	var b [4]byte
+	word := func() (uint32, error) {
+		_, err := io.ReadFull(buf, b[:])
+		return le.Uint32(b[:]), err
+	}
+
+	for i := 0; i < int(head[5]); i++ {
+		var m QBMatrix
+
+		n, err := buf.ReadByte()
+		if err != nil { return nil, fmt.Errorf("qb: matrix %d: %v", i, err) }
+		name := make([]byte, n)
+		var dims [3]uint32
+		var pos [3]int32
+		if _, err = io.ReadFull(buf, name); err == nil {
+			if err = binary.Read(buf, le, &dims); err == nil {
+				err = binary.Read(buf, le, &pos)
+			}
+		}
+		if err != nil { return nil, fmt.Errorf("qb: matrix %d: %v", i, err) }
+
+		for _, d := range dims {
+			if d == 0 || d > 1<<16 { return nil, fmt.Errorf("qb: matrix %d: bad size %v", i, dims) }
+		}
+		m.Name = string(name)
+		m.Size = Size { int(dims[0]), int(dims[1]), int(dims[2]) }
+		m.Pos = [3]int { int(pos[0]), int(pos[1]), int(pos[2]) }
+		if left { m.Pos[2] = -m.Pos[2] - m.Size.D }
+
+		set := func(x, y, z int, c uint32) {
+			le.PutUint32(b[:], c)
+			col := color.RGBA { b[0], b[1], b[2], b[3] }
+			if bgra { col.R, col.B = col.B, col.R }
+			if col.A == 0 { return }
+			if masked { col.A = 0xff }
+
+			if left { z = m.Size.D-1 - z }
+			dst.Set(offx + m.Pos[0] + x, offy + m.Pos[1] + y, offz + m.Pos[2] + z, value(col))
+		}
+
+		w, h, d := m.Size.W, m.Size.H, m.Size.D
+		for z := 0; z < d; z++ {
+			if !compressed {
+				for y := 0; y < h; y++ {
+					for x := 0; x < w; x++ {
+						c, err := word()
+						if err != nil { return nil, fmt.Errorf("qb: matrix %d: %v", i, err) }
+						set(x, y, z, c)
+					}
+				}
+				continue
+			}
+
+			// runs of a slice, each a colour or a repeat count and colour
+			for k := 0; ; {
+				c, err := word()
+				if err != nil { return nil, fmt.Errorf("qb: matrix %d: %v", i, err) }
+				if c == qbNextSlice { break }
+
+				cnt := uint32(1)
+				if c == qbRepeat {
+					if cnt, err = word(); err == nil { c, err = word() }
+					if err != nil { return nil, fmt.Errorf("qb: matrix %d: %v", i, err) }
+				}
+				if uint64(k) + uint64(cnt) > uint64(w*h) {
+					return nil, fmt.Errorf("qb: matrix %d: slice %d overflows", i, z)
+				}
+
+				for ; cnt > 0; cnt-- {
+					set(k % w, k / w, z, c)
+					k++
+				}
+			}
+		}
+
+		ms = append(ms, m)
+	}
+
+	return ms, nil
+}
+
+// qbBounds returns the cells [lo, hi) covered by ms.
+func qbBounds(ms []QBMatrix) (lo, hi [3]int) {
+	for i, m := range ms {
+		s := [3]int { m.Size.W, m.Size.H, m.Size.D }
+		for k := range lo {
+			if i == 0 || m.Pos[k] < lo[k] { lo[k] = m.Pos[k] }
+			if i == 0 || m.Pos[k] + s[k] > hi[k] { hi[k] = m.Pos[k] + s[k] }
+		}
+	}
+	return
+}
@@ -0,0 +1,109 @@
+package glvox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"testing"
+)
+
+func qbFile(head [5]uint32, matrices ...[]uint32) *bytes.Buffer {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(0x101))
+	binary.Write(&buf, binary.LittleEndian, head[1:])
+	binary.Write(&buf, binary.LittleEndian, uint32(len(matrices)))
+	for i, m := range matrices {
+		buf.WriteByte(1)
+		buf.WriteByte(byte('a' + i))
+		binary.Write(&buf, binary.LittleEndian, m)
+	}
+	return &buf
+}
+
+func TestReadQB(t *testing.T) {
+
+	red, blue := uint32(0xff0000ff), uint32(0xffff0000)
+
+	// uncompressed RGBA, right-handed: a 2×1×2 matrix at (1, 2, 3) and a
+	// single voxel at (-1, 0, 0)
+	f := qbFile([5]uint32 { 0, 0, 1, 0, 0 },
+		[]uint32 { 2, 1, 2, 1, 2, 3,  red, 0,  0, blue },
+		[]uint32 { 1, 1, 1, 0xffffffff, 0, 0,  red })
+
+	oct := NewOctree(8)
+	ms, err := ReadQB(f, oct, nil, 1, 0, 0)
+	if err != nil { t.Fatal(err) }
+
+	if len(ms) != 2 || ms[0].Name != "a" || ms[0].Pos != [3]int { 1, 2, 3 } || ms[0].Size != (Size { 2, 1, 2 }) {
+		t.Errorf("unexpected matrices %v", ms)
+	}
+	expect := func(x, y, z, exp int) {
+		if v, _ := oct.Get(x, y, z); v != exp {
+			t.Errorf("(%d, %d, %d): expected %x, was %x", x, y, z, exp, v)
+		}
+	}
+	expect(2, 2, 3, PackRGB([3]uint8 { 0xff, 0, 0 }))
+	expect(3, 2, 3, 0)
+	expect(3, 2, 4, PackRGB([3]uint8 { 0, 0, 0xff }))
+	expect(0, 0, 0, PackRGB([3]uint8 { 0xff, 0, 0 }))
+
+	// compressed BGRA with a visibility mask, left-handed: a 3×2×2 matrix
+	// at z 0, so mirrored to z -2..-1
+	f = qbFile([5]uint32 { 0, 1, 0, 1, 1 },
+		[]uint32 { 3, 2, 2, 0, 0, 0,
+			qbRepeat, 4, 0x01ff0000, 0x02000000, qbNextSlice,
+			0, qbRepeat, 2, 0x0100ff00, qbNextSlice })
+
+	var vals []color.RGBA
+	oct = NewOctree(8)
+	ms, err = ReadQB(f, oct, func(c color.RGBA) int { vals = append(vals, c); return len(vals) }, 0, 0, 2)
+	if err != nil { t.Fatal(err) }
+
+	if ms[0].Pos != [3]int { 0, 0, -2 } {
+		t.Errorf("mirrored position (0, 0, -2) expected, was %v", ms[0].Pos)
+	}
+	if len(vals) != 7 || vals[0] != (color.RGBA { 0xff, 0, 0, 0xff }) || vals[5] != (color.RGBA { 0, 0xff, 0, 0xff }) {
+		t.Errorf("unexpected colours %v", vals)
+	}
+	expect(0, 0, 1, 1)
+	expect(0, 1, 1, 4)
+	expect(2, 1, 1, 0)
+	expect(1, 0, 0, 6)
+	expect(0, 0, 0, 0)
+
+	overflow := qbFile([5]uint32 { 0, 0, 1, 1, 0 },
+		[]uint32 { 1, 1, 1, 0, 0, 0,  qbRepeat, 2, red, qbNextSlice })
+	if _, err := ReadQB(overflow, oct, nil, 0, 0, 0); err == nil {
+		t.Error("error expected for an overflowing run")
+	}
+	if _, err := ReadQB(bytes.NewBufferString("VOX "), oct, nil, 0, 0, 0); err == nil {
+		t.Error("error expected for a file that is not qb")
+	}
+}
+
+func TestReadQBSpec(t *testing.T) {
+
+	// a compressed 2×1×2 RGBA file written out by hand: the first slice is
+	// a run of two red voxels (code 2), the second a blue voxel and an
+	// empty one, each slice ended by 6
+	f := []byte {
+		1, 1, 0, 0,  0, 0, 0, 0,  1, 0, 0, 0,  1, 0, 0, 0,  0, 0, 0, 0,  1, 0, 0, 0,
+		1, 'm',
+		2, 0, 0, 0,  1, 0, 0, 0,  2, 0, 0, 0,
+		0, 0, 0, 0,  0, 0, 0, 0,  0, 0, 0, 0,
+		2, 0, 0, 0,  2, 0, 0, 0,  0xff, 0, 0, 0xff,
+		6, 0, 0, 0,
+		0, 0, 0xff, 0xff,  0, 0, 0, 0,
+		6, 0, 0, 0,
+	}
+
+	oct := NewOctree(4)
+	if _, err := ReadQB(bytes.NewReader(f), oct, nil, 0, 0, 0); err != nil { t.Fatal(err) }
+
+	red, blue := PackRGB([3]uint8 { 0xff, 0, 0 }), PackRGB([3]uint8 { 0, 0, 0xff })
+	for _, c := range []struct { x, y, z, v int } { { 0, 0, 0, red }, { 1, 0, 0, red }, { 0, 0, 1, blue }, { 1, 0, 1, 0 } } {
+		if v, _ := oct.Get(c.x, c.y, c.z); v != c.v {
+			t.Errorf("(%d, %d, %d): expected %x, was %x", c.x, c.y, c.z, c.v, v)
+		}
+	}
+}
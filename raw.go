@@ -0,0 +1,171 @@
+package glvox
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RawType is the sample type of a raw volume.
+type RawType int
+
+const (
+	RawUint8 RawType = iota
+	RawInt8
+	RawUint16
+	RawInt16
+	RawFloat32
+	RawFloat64
+)
+
+var rawSizes = [...]int { 1, 1, 2, 2, 4, 8 }
+
+// RawFormat describes a headerless volume: Size samples of Type, x
+// fastest, then y, then z.
+type RawFormat struct {
+	Size Size
+	Type RawType
+	BigEndian bool
+}
+
+// ReadRaw reads a raw volume into a float grid of its sample values.
+func ReadRaw(r io.Reader, f RawFormat) (g *FloatGrid, err error) {
+
+	if f.Type < 0 || int(f.Type) >= len(rawSizes) { return nil, fmt.Errorf("raw: bad type %d", f.Type) }
+	if f.Size.W <= 0 || f.Size.H <= 0 || f.Size.D <= 0 { return nil, fmt.Errorf("raw: bad size %v", f.Size) }
+
+	// read before allocating, so a wrong size fails on short data
+	n := int64(f.Size.W) * int64(f.Size.H) * int64(f.Size.D)
+	if n > math.MaxInt32 { return nil, fmt.Errorf("raw: size %v too large", f.Size) }
+	data, err := ioutil.ReadAll(io.LimitReader(r, n * int64(rawSizes[f.Type])))
+	if err != nil { return nil, err }
+	if int64(len(data)) != n * int64(rawSizes[f.Type]) {
+		return nil, fmt.Errorf("raw: %d of %d bytes", len(data), n * int64(rawSizes[f.Type]))
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if f.BigEndian { order = binary.BigEndian }
+
+	g = NewFloatGrid(int32(f.Size.W), int32(f.Size.H), int32(f.Size.D))
+	for i := range g.data {
+		var v float32
+		switch f.Type {
+		case RawUint8: v = float32(data[i])
+		case RawInt8: v = float32(int8(data[i]))
+		case RawUint16: v = float32(order.Uint16(data[2*i:]))
+		case RawInt16: v = float32(int16(order.Uint16(data[2*i:])))
+		case RawFloat32: v = math.Float32frombits(order.Uint32(data[4*i:]))
+		case RawFloat64: v = float32(math.Float64frombits(order.Uint64(data[8*i:])))
+		}
+		g.data[i] = v
+	}
+
+	return g, nil
+}
+
+var nrrdTypes = map[string]RawType {
+	"uchar": RawUint8, "unsigned char": RawUint8, "uint8": RawUint8, "uint8_t": RawUint8,
+	"signed char": RawInt8, "int8": RawInt8, "int8_t": RawInt8,
+	"short": RawInt16, "short int": RawInt16, "signed short": RawInt16, "signed short int": RawInt16, "int16": RawInt16, "int16_t": RawInt16,
+	"ushort": RawUint16, "unsigned short": RawUint16, "unsigned short int": RawUint16, "uint16": RawUint16, "uint16_t": RawUint16,
+	"float": RawFloat32, "double": RawFloat64,
+}
+
+// ReadNRRD reads a 3D NRRD volume with attached data, raw or gzip
+// encoded, into a float grid of its sample values.
+func ReadNRRD(r io.Reader) (g *FloatGrid, err error) {
+
+	buf := bufio.NewReader(r)
+
+	line, err := buf.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "NRRD000") { return nil, errors.New("not a nrrd file") }
+
+	var f RawFormat
+	typ, encoding, dim := "", "raw", 0
+	for n := 2; ; n++ {
+		line, err = buf.ReadString('\n')
+		if err != nil { return nil, fmt.Errorf("nrrd line %d: header without data", n) }
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" { break }
+		if strings.HasPrefix(line, "#") { continue }
+
+		// fields are "key: value"; key/value pairs, "key:=value", are skipped
+		kv := strings.SplitN(line, ": ", 2)
+		if len(kv) != 2 { continue }
+		val := strings.TrimSpace(kv[1])
+
+		switch kv[0] {
+		case "type": typ = val
+		case "encoding": encoding = val
+		case "endian": f.BigEndian = val == "big"
+		case "dimension":
+			if dim, err = strconv.Atoi(val); err != nil { return nil, fmt.Errorf("nrrd line %d: %v", n, err) }
+		case "sizes":
+			s := strings.Fields(val)
+			if len(s) != 3 { return nil, fmt.Errorf("nrrd line %d: 3 sizes expected", n) }
+			var d [3]int
+			for i := range d {
+				if d[i], err = strconv.Atoi(s[i]); err != nil { return nil, fmt.Errorf("nrrd line %d: %v", n, err) }
+			}
+			f.Size = Size { d[0], d[1], d[2] }
+		case "data file", "datafile":
+			return nil, fmt.Errorf("nrrd line %d: detached data is not supported", n)
+		}
+	}
+
+	if dim != 3 { return nil, fmt.Errorf("nrrd: dimension %d, 3 expected", dim) }
+	t, ok := nrrdTypes[typ]
+	if !ok { return nil, fmt.Errorf("nrrd: unsupported type %q", typ) }
+	f.Type = t
+
+	var data io.Reader = buf
+	switch encoding {
+	case "raw":
+	case "gzip", "gz":
+		z, err := gzip.NewReader(buf)
+		if err != nil { return nil, fmt.Errorf("nrrd: %v", err) }
+		defer z.Close()
+		data = z
+	default:
+		return nil, fmt.Errorf("nrrd: unsupported encoding %q", encoding)
+	}
+
+	return ReadRaw(data, f)
+}
+
+// Threshold sets every voxel of dst whose sample in g is at least iso to v.
+func Threshold(g *FloatGrid, dst Setter, iso float32, v int) {
+	for z := int32(0); z < g.D; z++ {
+		for y := int32(0); y < g.H; y++ {
+			for x := int32(0); x < g.W; x++ {
+				if g.Get(x, y, z) >= iso { dst.Set(int(x), int(y), int(z), v) }
+			}
+		}
+	}
+}
+
+// Quantize maps the samples of g in [lo, hi] onto the values 1..levels
+// in dst, clamping those above; samples below lo stay empty.
+func Quantize(g *FloatGrid, dst Setter, lo, hi float32, levels int) {
+	for z := int32(0); z < g.D; z++ {
+		for y := int32(0); y < g.H; y++ {
+			for x := int32(0); x < g.W; x++ {
+				s := g.Get(x, y, z)
+				if s < lo { continue }
+
+				v := levels
+				if hi > lo { v = 1 + int((s - lo) / (hi - lo) * float32(levels)) }
+				if v > levels { v = levels }
+				dst.Set(int(x), int(y), int(z), v)
+			}
+		}
+	}
+}
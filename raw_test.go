@@ -0,0 +1,74 @@
+package glvox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadRaw(t *testing.T) {
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, []uint16 { 0, 1000, 2000, 3000, 4000, 65535 })
+
+	g, err := ReadRaw(bytes.NewReader(buf.Bytes()), RawFormat { Size { 3, 2, 1 }, RawUint16, true })
+	if err != nil { t.Fatal(err) }
+	if g.Get(1, 0, 0) != 1000 || g.Get(2, 1, 0) != 65535 {
+		t.Errorf("unexpected samples %v", g.data)
+	}
+
+	oct := NewOctree(4)
+	Threshold(g, oct, 2500, 3)
+	if v, _ := oct.Get(0, 1, 0); v != 3 {
+		t.Errorf("3 at (0, 1, 0) expected, was %d", v)
+	}
+	if v, _ := oct.Get(2, 0, 0); v != 0 {
+		t.Errorf("0 at (2, 0, 0) expected, was %d", v)
+	}
+
+	oct = NewOctree(4)
+	Quantize(g, oct, 1000, 5000, 4)
+	for i, exp := range []int { 0, 1, 2, 3, 4, 4 } {
+		if v, _ := oct.Get(i % 3, i / 3, 0); v != exp {
+			t.Errorf("sample %d: %d expected, was %d", i, exp, v)
+		}
+	}
+
+	if _, err := ReadRaw(bytes.NewReader(buf.Bytes()), RawFormat { Size { 4, 2, 1 }, RawUint16, true }); err == nil {
+		t.Error("error expected for short data")
+	}
+}
+
+func TestReadNRRD(t *testing.T) {
+
+	var data bytes.Buffer
+	z := gzip.NewWriter(&data)
+	binary.Write(z, binary.LittleEndian, []float32 { -1, 0.5, 2, 8 })
+	z.Close()
+
+	head := "NRRD0004\n# a comment\ntype: float\ndimension: 3\nsizes: 2 1 2\nendian: little\nencoding: gzip\nspace dimension: 3\nkey:=value\n\n"
+	g, err := ReadNRRD(bytes.NewReader(append([]byte(head), data.Bytes()...)))
+	if err != nil { t.Fatal(err) }
+	if g.Size() != (Size { 2, 1, 2 }) || g.Get(0, 0, 0) != -1 || g.Get(1, 0, 1) != 8 {
+		t.Errorf("unexpected grid %v %v", g.Size(), g.data)
+	}
+
+	g, err = ReadNRRD(bytes.NewBufferString("NRRD0001\ntype: unsigned char\ndimension: 3\nsizes: 1 1 3\nencoding: raw\n\n\x01\x02\x03"))
+	if err != nil { t.Fatal(err) }
+	if g.Get(0, 0, 2) != 3 {
+		t.Errorf("unexpected samples %v", g.data)
+	}
+
+	for _, bad := range []string {
+		"P6\n",
+		"NRRD0004\ntype: float\ndimension: 2\nsizes: 1 1\n\n",
+		"NRRD0004\ntype: int64\ndimension: 3\nsizes: 1 1 1\n\n",
+		"NRRD0004\ntype: float\ndimension: 3\nsizes: 1 1 1\ndata file: a.raw\n\n",
+		"NRRD0004\ntype: float\ndimension: 3\nsizes: 1 1 1\n",
+	} {
+		if _, err := ReadNRRD(bytes.NewBufferString(bad)); err == nil {
+			t.Errorf("%q: error expected", bad)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package glvox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// BlockTable maps Minecraft blocks to voxel values. Sponge schematics look
+// blocks up by name without block states, like "minecraft:stone"; classic
+// schematics by number, "1:2" with the data value before "1" without.
+// Other blocks get Default; air is always empty.
+type BlockTable struct {
+	Values map[string]int
+	Default int
+}
+
+func (t *BlockTable) value(keys ...string) int {
+	for _, k := range keys {
+		if v, ok := t.Values[k]; ok { return v }
+	}
+	return t.Default
+}
+
+var airBlocks = map[string]bool {
+	"minecraft:air": true, "minecraft:cave_air": true, "minecraft:void_air": true,
+}
+
+// LoadSchematic reads a classic MCEdit .schematic or a Sponge schematic,
+// version 1 to 3, and sets its blocks into dst through table, the lowest
+// corner at (offx, offy, offz). Minecraft's y up matches glvox. The size
+// of the schematic is returned.
+func LoadSchematic(r io.Reader, dst Setter, table *BlockTable,
+	offx, offy, offz int) (size Size, err error) {
+
+	_, root, err := ReadNBT(r)
+	if err != nil { return }
+
+	// Sponge 3 nests the schematic in the root
+	if s, ok := root["Schematic"].(map[string]interface{}); ok { root = s }
+
+	w, ok1 := root["Width"].(int16)
+	h, ok2 := root["Height"].(int16)
+	l, ok3 := root["Length"].(int16)
+	if !ok1 || !ok2 || !ok3 { return size, errors.New("schematic: no dimensions") }
+	size = Size { int(uint16(w)), int(uint16(h)), int(uint16(l)) }
+	n := size.W * size.H * size.D
+
+	// blocks are ordered x fastest, then z, then y
+	set := func(i int, v int) {
+		if v == 0 { return }
+		x, z, y := i % size.W, i / size.W % size.D, i / (size.W*size.D)
+		dst.Set(offx + x, offy + y, offz + z, v)
+	}
+
+	if blocks, ok := root["Blocks"].([]byte); ok {
+		data, _ := root["Data"].([]byte)
+		add, _ := root["AddBlocks"].([]byte)
+		if len(blocks) != n || data != nil && len(data) != n {
+			return size, errors.New("schematic: block arrays do not match the dimensions")
+		}
+
+		for i, b := range blocks {
+			id := int(b)
+			// the high bits of even blocks in the low nibble
+			if i>>1 < len(add) { id |= int(add[i>>1] >> uint(4*(i&1)) & 0xf) << 8 }
+			if id == 0 { continue }
+
+			k := strconv.Itoa(id)
+			if data != nil {
+				set(i, table.value(k + ":" + strconv.Itoa(int(data[i])), k))
+			} else {
+				set(i, table.value(k))
+			}
+		}
+		return size, nil
+	}
+
+	palette, _ := root["Palette"].(map[string]interface{})
+	data, _ := root["BlockData"].([]byte)
+	if b, ok := root["Blocks"].(map[string]interface{}); ok {
+		palette, _ = b["Palette"].(map[string]interface{})
+		data, _ = b["Data"].([]byte)
+	}
+	if palette == nil || data == nil { return size, errors.New("schematic: no blocks") }
+
+	values := make(map[int32]int, len(palette))
+	for name, id := range palette {
+		i, ok := id.(int32)
+		if !ok { return size, fmt.Errorf("schematic: bad palette entry %q", name) }
+		name = strings.SplitN(name, "[", 2)[0]
+		if !airBlocks[name] { values[i] = table.value(name) }
+	}
+
+	// indices into the palette as varints
+	i := 0
+	for p := 0; p < len(data); i++ {
+		id, shift := int32(0), uint(0)
+		for {
+			if p >= len(data) || shift > 28 { return size, errors.New("schematic: bad block data") }
+			b := data[p]
+			p++
+			id |= int32(b & 0x7f) << shift
+			shift += 7
+			if b & 0x80 == 0 { break }
+		}
+		if i >= n { return size, errors.New("schematic: too many blocks") }
+		set(i, values[id])
+	}
+	if i != n { return size, errors.New("schematic: too few blocks") }
+
+	return size, nil
+}
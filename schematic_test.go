@@ -0,0 +1,72 @@
+package glvox
+
+import (
+	"testing"
+)
+
+func TestLoadSchematic(t *testing.T) {
+
+	table := &BlockTable { map[string]int { "1": 5, "35:14": 7, "minecraft:stone": 5, "minecraft:red_wool": 7 }, 9 }
+
+	// 2×2×3 classic, indexed (y*3 + z)*2 + x: stone at (1, 0, 0), red wool
+	// at (0, 1, 2), white wool at (1, 1, 2) and block 256+1 at (0, 0, 1)
+	blocks, data := make([]byte, 12), make([]byte, 12)
+	blocks[1] = 1
+	blocks[10] = 35; data[10] = 14
+	blocks[11] = 35
+	blocks[2] = 1
+	add := []byte { 0, 0x01 }
+
+	classic := nbtFile("Schematic", map[string]interface{} {
+		"Width": int16(2), "Height": int16(2), "Length": int16(3), "Materials": "Alpha",
+		"Blocks": blocks, "Data": data, "AddBlocks": add,
+	}, true)
+
+	oct := NewOctree(8)
+	size, err := LoadSchematic(classic, oct, table, 1, 0, 0)
+	if err != nil { t.Fatal(err) }
+	if size != (Size { 2, 2, 3 }) {
+		t.Errorf("size 2×2×3 expected, was %v", size)
+	}
+
+	expect := func(x, y, z, exp int) {
+		if v, _ := oct.Get(x, y, z); v != exp {
+			t.Errorf("(%d, %d, %d): expected %d, was %d", x, y, z, exp, v)
+		}
+	}
+	expect(2, 0, 0, 5)
+	expect(1, 1, 2, 7)
+	expect(2, 1, 2, 9)
+	expect(1, 0, 1, 9)
+	expect(1, 0, 0, 0)
+
+	// the same in Sponge 2 and 3, indices 0 and 200 as varints
+	palette := map[string]interface{} {
+		"minecraft:air": int32(0), "minecraft:stone": int32(200),
+		"minecraft:red_wool": int32(1), "minecraft:white_wool[lit=true]": int32(2),
+	}
+	ids := []byte { 0, 0xc8, 0x01,  0, 0, 0, 0,  0, 0, 0, 0,  1, 2 }
+	sponge := []map[string]interface{} {
+		{ "Version": int32(2), "Width": int16(2), "Height": int16(2), "Length": int16(3),
+			"Palette": palette, "BlockData": ids },
+		{ "Schematic": map[string]interface{} { "Version": int32(3), "Width": int16(2), "Height": int16(2), "Length": int16(3),
+			"Blocks": map[string]interface{} { "Palette": palette, "Data": ids } } },
+	}
+
+	for i, root := range sponge {
+		oct = NewOctree(8)
+		if _, err := LoadSchematic(nbtFile("", root, true), oct, table, 1, 0, 0); err != nil {
+			t.Fatalf("sponge %d: %v", i+2, err)
+		}
+		expect(2, 0, 0, 5)
+		expect(1, 1, 2, 7)
+		expect(2, 1, 2, 9)
+		expect(1, 0, 0, 0)
+	}
+
+	short := nbtFile("", map[string]interface{} {
+		"Width": int16(2), "Height": int16(2), "Length": int16(3), "Palette": palette, "BlockData": ids[:12] }, false)
+	if _, err := LoadSchematic(short, oct, table, 0, 0, 0); err == nil {
+		t.Error("error expected for too few blocks")
+	}
+}
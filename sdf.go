@@ -0,0 +1,154 @@
+package glvox
+
+import (
+	"math"
+)
+
+// SDF is a signed distance function, negative inside. The functions here
+// are exact or underestimate the distance, which Generate and DualContour
+// rely on when they skip space. An SDF is a Hermite and a Distancer.
+type SDF func(p Vec3) float32
+
+func SphereSDF(c Vec3, r float32) SDF {
+	return func(p Vec3) float32 { return p.Minus(c).Norm() - r }
+}
+
+// BoxSDF is the box with center c and half extents h.
+func BoxSDF(c, h Vec3) SDF {
+	return RoundBoxSDF(c, h, 0)
+}
+
+// RoundBoxSDF is the box with center c and half extents h, its edges
+// rounded by radius r.
+func RoundBoxSDF(c, h Vec3, r float32) SDF {
+	h = h.Minus(Vec3 { r, r, r })
+	return func(p Vec3) float32 {
+		d := p.Minus(c)
+		q := Vec3 { abs(d.X) - h.X, abs(d.Y) - h.Y, abs(d.Z) - h.Z }
+		out := Vec3 { max32(q.X, 0), max32(q.Y, 0), max32(q.Z, 0) }.Norm()
+		in := min32(max32(q.X, max32(q.Y, q.Z)), 0)
+		return out + in - r
+	}
+}
+
+// TorusSDF is the torus around the z axis through c, with radius R of the
+// ring and r of the tube.
+func TorusSDF(c Vec3, R, r float32) SDF {
+	return func(p Vec3) float32 {
+		d := p.Minus(c)
+		q := sqrt32(d.X*d.X + d.Y*d.Y) - R
+		return sqrt32(q*q + d.Z*d.Z) - r
+	}
+}
+
+// CylinderSDF is the capped cylinder along the z axis with center c,
+// radius r and half height h.
+func CylinderSDF(c Vec3, r, h float32) SDF {
+	return func(p Vec3) float32 {
+		d := p.Minus(c)
+		dr := sqrt32(d.X*d.X + d.Y*d.Y) - r
+		dz := abs(d.Z) - h
+		out := sqrt32(max32(dr, 0)*max32(dr, 0) + max32(dz, 0)*max32(dz, 0))
+		return out + min32(max32(dr, dz), 0)
+	}
+}
+
+// PlaneSDF is the half space behind the plane through p0 with normal n.
+func PlaneSDF(p0, n Vec3) SDF {
+	n = n.Normalize()
+	return func(p Vec3) float32 { return p.Minus(p0).Dot(n) }
+}
+
+func (f SDF) Union(g SDF) SDF {
+	return func(p Vec3) float32 { return min32(f(p), g(p)) }
+}
+
+func (f SDF) Intersection(g SDF) SDF {
+	return func(p Vec3) float32 { return max32(f(p), g(p)) }
+}
+
+func (f SDF) Subtract(g SDF) SDF {
+	return func(p Vec3) float32 { return max32(f(p), -g(p)) }
+}
+
+// SmoothUnion blends f and g within distance k of both.
+func (f SDF) SmoothUnion(g SDF, k float32) SDF {
+	return func(p Vec3) float32 {
+		a, b := f(p), g(p)
+		h := max32(k - abs(a - b), 0) / k
+		return min32(a, b) - h*h*k/4
+	}
+}
+
+// SmoothSubtract cuts g out of f, rounding the seam within distance k.
+func (f SDF) SmoothSubtract(g SDF, k float32) SDF {
+	return func(p Vec3) float32 {
+		a, b := f(p), -g(p)
+		h := max32(k - abs(a - b), 0) / k
+		return max32(a, b) + h*h*k/4
+	}
+}
+
+func (f SDF) Translate(t Vec3) SDF {
+	return func(p Vec3) float32 { return f(p.Minus(t)) }
+}
+
+// Rotate turns f by q around the origin.
+func (f SDF) Rotate(q Quat) SDF {
+	m := q.Mat3().Transpose()
+	return func(p Vec3) float32 { return f(m.Mul(p)) }
+}
+
+// Scale scales f by s around the origin.
+func (f SDF) Scale(s float32) SDF {
+	return func(p Vec3) float32 { return f(p.Mul(1 / s)) * s }
+}
+
+func (f SDF) Distance(p Vec3) float32 {
+	return f(p)
+}
+
+func (f SDF) Inside(p Vec3) bool {
+	return f(p) < 0
+}
+
+func (f SDF) Intersect(a, b Vec3) (p, n Vec3) {
+	p = bisect(f.Inside, a, b)
+	n = gradient(f, p, b.Minus(a).Norm() * 1e-3)
+	return
+}
+
+// Generate sets every voxel of oct whose center lies inside f to v. Voxel
+// (x, y, z) covers org + [x, x+1)*voxel and so on. Nodes are visited top
+// down; a node whose center is further from the surface than its corners
+// is entirely inside or outside, and is written as one leaf or skipped.
+func Generate(f SDF, oct *Octree, org Vec3, voxel float32, v int) {
+
+	type node struct { x, y, z, size int }
+	stack := []node { { 0, 0, 0, oct.Size } }
+	sqrt3 := float32(math.Sqrt(3))
+
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		h := float32(n.size) / 2
+		c := org.Plus(Vec3 { float32(n.x) + h, float32(n.y) + h, float32(n.z) + h }.Mul(voxel))
+		d := f(c)
+
+		if n.size == 1 {
+			if d < 0 { oct.Set(n.x, n.y, n.z, v) }
+			continue
+		}
+
+		r := h * sqrt3 * voxel
+		if d >= r { continue }
+		if d <= -r { oct.setNode(n.x, n.y, n.z, n.size, v); continue }
+
+		s := n.size / 2
+		for off := 0; off < 8; off++ {
+			stack = append(stack, node {
+				n.x + (off & 1) * s, n.y + (off >> 1 & 1) * s, n.z + (off >> 2) * s, s })
+		}
+	}
+}
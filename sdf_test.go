@@ -0,0 +1,73 @@
+package glvox
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSDFPrimitives(t *testing.T) {
+
+	tests := []struct {
+		f SDF
+		p Vec3
+		d float32
+	}{
+		{ SphereSDF(Vec3 { 1, 1, 1 }, 2), Vec3 { 1, 1, 4 }, 1 },
+		{ BoxSDF(Vec3 {}, Vec3 { 1, 2, 3 }), Vec3 { 0, 0, 0 }, -1 },
+		{ BoxSDF(Vec3 {}, Vec3 { 1, 2, 3 }), Vec3 { 2, 3, 0 }, float32(math.Sqrt(2)) },
+		{ RoundBoxSDF(Vec3 {}, Vec3 { 1, 1, 1 }, .5), Vec3 { 2, 0, 0 }, 1 },
+		{ RoundBoxSDF(Vec3 {}, Vec3 { 1, 1, 1 }, .5), Vec3 { 1, 1, 0 }, float32(math.Sqrt(.5)) - .5 },
+		{ TorusSDF(Vec3 {}, 3, 1), Vec3 { 3, 0, 0 }, -1 },
+		{ TorusSDF(Vec3 {}, 3, 1), Vec3 { 0, 0, 0 }, 2 },
+		{ CylinderSDF(Vec3 {}, 1, 2), Vec3 { 0, 0, 3 }, 1 },
+		{ CylinderSDF(Vec3 {}, 1, 2), Vec3 { .5, 0, 0 }, -.5 },
+		{ PlaneSDF(Vec3 { 0, 0, 1 }, Vec3 { 0, 0, 2 }), Vec3 { 5, 5, 0 }, -1 },
+		{ SphereSDF(Vec3 {}, 1).Union(SphereSDF(Vec3 { 4, 0, 0 }, 1)), Vec3 { 4, 0, 0 }, -1 },
+		{ BoxSDF(Vec3 {}, Vec3 { 2, 2, 2 }).Subtract(SphereSDF(Vec3 {}, 1)), Vec3 {}, 1 },
+		{ SphereSDF(Vec3 {}, 1).Translate(Vec3 { 0, 5, 0 }), Vec3 { 0, 5, 0 }, -1 },
+		{ BoxSDF(Vec3 {}, Vec3 { 1, 3, 1 }).Rotate(NewQuat(math.Pi/2, Vec3 { 0, 0, 1 })), Vec3 { 2.5, 0, 0 }, -.5 },
+		{ SphereSDF(Vec3 { 1, 0, 0 }, 1).Scale(2), Vec3 { 2, 0, 0 }, -2 },
+	}
+
+	for i, tt := range tests {
+		if d := tt.f(tt.p); math.Abs(float64(d - tt.d)) > 1e-5 {
+			t.Errorf("test%d: %f expected, was %f", i+1, tt.d, d)
+		}
+	}
+
+	a, b := SphereSDF(Vec3 { -1, 0, 0 }, 1), SphereSDF(Vec3 { 1, 0, 0 }, 1)
+	if a.SmoothUnion(b, 1)(Vec3 {}) >= a.Union(b)(Vec3 {}) {
+		t.Error("smooth union expected to bulge between the spheres")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+
+	oct := NewOctree(64)
+	Generate(SphereSDF(Vec3 { 32, 32, 32 }, 20), oct, Vec3 {}, 1, 1)
+
+	exp := 4.0 / 3.0 * math.Pi * 20 * 20 * 20
+	if n := float64(count(OctreeVolume { oct })); math.Abs(n - exp) > .01 * exp {
+		t.Errorf("about %.0f voxels expected, was %.0f", exp, n)
+	}
+
+	if _, s := oct.Get(32, 32, 32); s < 8 {
+		t.Errorf("large leaf expected in the interior, was size %d", s)
+	}
+
+	// matches voxel by voxel classification
+	f := TorusSDF(Vec3 {}, 9, 4).Rotate(NewQuat(.5, Vec3 { 1, 0, 0 })).Translate(Vec3 { 16, 16, 16 })
+	oct = NewOctree(32)
+	Generate(f, oct, Vec3 {}, 1, 2)
+	for z := 0; z < 32; z++ {
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				exp := 0
+				if f(Vec3 { float32(x) + .5, float32(y) + .5, float32(z) + .5 }) < 0 { exp = 2 }
+				if v, _ := oct.Get(x, y, z); v != exp {
+					t.Fatalf("(%d, %d, %d): %d expected, was %d", x, y, z, exp, v)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package glvox
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sliceAxes returns, for slices across axis 0, 1 or 2, the volume axes
+// along image columns and rows and whether rows run down the volume.
+// Slices across x and z keep y up; slices across y look down on the
+// xz plane.
+func sliceAxes(axis int) (u, v int, flip bool) {
+	switch axis {
+	case 0: return 2, 1, true
+	case 1: return 0, 2, false
+	}
+	return 0, 1, true
+}
+
+// ExportSlices writes vol as a stack of PNG images across axis 0, 1 or 2
+// (x, y or z) into dir, named by slice index. Pixels are colour(value),
+// or white for voxels and black for empty space if colour is nil, as
+// layer images for resin printers.
+func ExportSlices(vol SizedGetter, axis int, dir string, colour func(v int) color.Color) error {
+
+	if axis < 0 || axis > 2 { return fmt.Errorf("slices: bad axis %d", axis) }
+
+	s := vol.Size()
+	dims := [3]int { s.W, s.H, s.D }
+	u, v, flip := sliceAxes(axis)
+	w, h := dims[u], dims[v]
+
+	digits := len(strconv.Itoa(dims[axis]-1))
+	if digits < 4 { digits = 4 }
+
+	for k := 0; k < dims[axis]; k++ {
+		var img draw.Image = image.NewGray(image.Rect(0, 0, w, h))
+		if colour != nil { img = image.NewRGBA(image.Rect(0, 0, w, h)) }
+
+		var p [3]int
+		p[axis] = k
+		for row := 0; row < h; row++ {
+			p[v] = row
+			if flip { p[v] = h-1 - row }
+			for col := 0; col < w; col++ {
+				p[u] = col
+				val, _ := vol.Get(p[0], p[1], p[2])
+				switch {
+				case colour != nil: img.Set(col, row, colour(val))
+				case val != 0: img.Set(col, row, color.White)
+				}
+			}
+		}
+
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%0*d.png", digits, k)))
+		if err != nil { return err }
+		err = png.Encode(f, img)
+		if cerr := f.Close(); err == nil { err = cerr }
+		if err != nil { return err }
+	}
+
+	return nil
+}
+
+// sliceNumber returns the last run of digits in name, as layer images are
+// often numbered without zero padding.
+func sliceNumber(name string) (n int, ok bool) {
+	end := len(name)
+	for end > 0 && (name[end-1] < '0' || name[end-1] > '9') { end-- }
+	start := end
+	for start > 0 && name[start-1] >= '0' && name[start-1] <= '9' { start-- }
+	n, err := strconv.Atoi(name[start:end])
+	return n, err == nil
+}
+
+// sliceLess orders numbered names by their numbers, then as strings,
+// before unnumbered names in string order.
+func sliceLess(a, b string) bool {
+	na, oka := sliceNumber(a)
+	nb, okb := sliceNumber(b)
+	if oka != okb { return oka }
+	if oka && na != nb { return na < nb }
+	return a < b
+}
+
+// ImportSlices reads the PNG images of dir, ordered by the number in their
+// names and unnumbered ones last, as slices across axis 0, 1 or 2, the
+// inverse of ExportSlices, and sets voxels of dst to value(colour), where
+// value(colour) is not 0. If value is nil bright, opaque pixels become
+// voxels of 1. The size of the volume is returned.
+func ImportSlices(dir string, dst Setter, axis int, value func(c color.Color) int) (size Size, err error) {
+
+	if axis < 0 || axis > 2 { return size, fmt.Errorf("slices: bad axis %d", axis) }
+	if value == nil {
+		value = func(c color.Color) int {
+			_, _, _, a := c.RGBA()
+			if a < 0x8000 || dark(c) { return 0 }
+			return 1
+		}
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil { return }
+
+	var names []string
+	for _, fi := range infos {
+		if !fi.IsDir() && strings.EqualFold(filepath.Ext(fi.Name()), ".png") {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return sliceLess(names[i], names[j]) })
+	if len(names) == 0 { return size, fmt.Errorf("slices: no images in %s", dir) }
+
+	u, v, flip := sliceAxes(axis)
+	var dims [3]int
+	dims[axis] = len(names)
+
+	for k, name := range names {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil { return size, err }
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil { return size, fmt.Errorf("slices: %s: %v", name, err) }
+
+		b := img.Bounds()
+		if k == 0 {
+			dims[u], dims[v] = b.Dx(), b.Dy()
+		} else if b.Dx() != dims[u] || b.Dy() != dims[v] {
+			return size, fmt.Errorf("slices: %s is %dx%d, %dx%d expected", name, b.Dx(), b.Dy(), dims[u], dims[v])
+		}
+
+		var p [3]int
+		p[axis] = k
+		for row := 0; row < dims[v]; row++ {
+			p[v] = row
+			if flip { p[v] = dims[v]-1 - row }
+			for col := 0; col < dims[u]; col++ {
+				p[u] = col
+				if val := value(img.At(b.Min.X + col, b.Min.Y + row)); val != 0 {
+					dst.Set(p[0], p[1], p[2], val)
+				}
+			}
+		}
+	}
+
+	return Size { dims[0], dims[1], dims[2] }, nil
+}
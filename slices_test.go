@@ -0,0 +1,123 @@
+package glvox
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestSlices(t *testing.T) {
+
+	src := GridVolume { NewGrid(5, 4, 3) }
+	src.Set(0, 0, 0, 1)
+	src.Set(4, 3, 2, 2)
+	src.Set(1, 2, 1, 3)
+
+	pal := []color.RGBA { {}, { 255, 0, 0, 255 }, { 0, 255, 0, 255 }, { 0, 0, 255, 255 } }
+	colour := func(v int) color.Color { return pal[v] }
+	value := func(c color.Color) int {
+		for i, p := range pal {
+			if color.RGBAModel.Convert(c) == p { return i }
+		}
+		return 0
+	}
+
+	for axis := 0; axis < 3; axis++ {
+		dir, err := ioutil.TempDir("", "glvox")
+		if err != nil { t.Fatal(err) }
+		defer os.RemoveAll(dir)
+
+		if err := ExportSlices(src, axis, dir, colour); err != nil { t.Fatal(err) }
+		if names, _ := filepath.Glob(filepath.Join(dir, "*.png")); len(names) != [3]int { 5, 4, 3 }[axis] {
+			t.Errorf("axis %d: %d slices", axis, len(names))
+		}
+
+		dst := GridVolume { NewGrid(5, 4, 3) }
+		size, err := ImportSlices(dir, dst, axis, value)
+		if err != nil { t.Fatal(err) }
+		if size != (Size { 5, 4, 3 }) {
+			t.Errorf("axis %d: size 5×4×3 expected, was %v", axis, size)
+		}
+		for i, exp := range src.data {
+			if int(dst.data[i]) != int(exp) {
+				t.Fatalf("axis %d: voxel %d: expected %d, was %d", axis, i, exp, dst.data[i])
+			}
+		}
+	}
+
+	// black and white layers, y up in slices across z
+	dir, err := ioutil.TempDir("", "glvox")
+	if err != nil { t.Fatal(err) }
+	defer os.RemoveAll(dir)
+
+	if err := ExportSlices(src, 2, dir, nil); err != nil { t.Fatal(err) }
+	f, err := os.Open(filepath.Join(dir, "0002.png"))
+	if err != nil { t.Fatal(err) }
+	img, err := png.Decode(f)
+	f.Close()
+	if err != nil { t.Fatal(err) }
+	if g := color.GrayModel.Convert(img.At(4, 0)).(color.Gray); g.Y != 0xff {
+		t.Error("white pixel at the top right expected")
+	}
+
+	dst := NewOctree(8)
+	if _, err := ImportSlices(dir, dst, 2, nil); err != nil { t.Fatal(err) }
+	if v, _ := dst.Get(4, 3, 2); v != 1 {
+		t.Errorf("1 at (4, 3, 2) expected, was %d", v)
+	}
+	if v, _ := dst.Get(3, 3, 2); v != 0 {
+		t.Errorf("0 at (3, 3, 2) expected, was %d", v)
+	}
+}
+
+func TestImportSlicesUnpadded(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "glvox")
+	if err != nil { t.Fatal(err) }
+	defer os.RemoveAll(dir)
+
+	// layer n has a single white pixel at column n-1, so 1..12 in string
+	// order would come out as 1, 10, 11, 12, 2, ...
+	for n := 1; n <= 12; n++ {
+		img := image.NewGray(image.Rect(0, 0, 12, 1))
+		img.Pix[n-1] = 0xff
+		f, err := os.Create(filepath.Join(dir, strconv.Itoa(n) + ".png"))
+		if err != nil { t.Fatal(err) }
+		png.Encode(f, img)
+		f.Close()
+	}
+
+	dst := GridVolume { NewGrid(12, 1, 12) }
+	if _, err := ImportSlices(dir, dst, 2, nil); err != nil { t.Fatal(err) }
+	for z := 0; z < 12; z++ {
+		if v, _ := dst.Get(z, 0, z); v != 1 {
+			t.Errorf("layer %d out of order", z+1)
+		}
+	}
+
+	if !sliceLess("layer_2.png", "layer_10.png") || !sliceLess("a.png", "b.png") {
+		t.Error("numeric, then string order expected")
+	}
+
+	// numbered names come first, so mixed names sort consistently
+	names := []string { "b.png", "c1.png", "a2.png", "a.png", "c01.png", "10.png" }
+	sort.Slice(names, func(i, j int) bool { return sliceLess(names[i], names[j]) })
+	exp := []string { "c01.png", "c1.png", "a2.png", "10.png", "a.png", "b.png" }
+	for i := range exp {
+		if names[i] != exp[i] { t.Fatalf("%v expected, was %v", exp, names) }
+	}
+	for _, a := range names {
+		for _, b := range names {
+			if sliceLess(a, b) && sliceLess(b, a) { t.Errorf("%s and %s both less", a, b) }
+		}
+	}
+	if !sliceLess("c1.png", "a2.png") || !sliceLess("a2.png", "b.png") || sliceLess("b.png", "c1.png") {
+		t.Error("c1 < a2 < b expected")
+	}
+}
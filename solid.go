@@ -0,0 +1,129 @@
+package glvox
+
+import (
+	"math"
+	"sort"
+)
+
+// VoxelizeSolid sets every voxel of oct inside the closed mesh tris to v,
+// within the lattice of size voxels of edge length voxel whose corner is
+// at org. Rays through the voxel centers are cast along z and the spans
+// between successive crossings with the mesh are filled. Triangle edges
+// follow a top-left rule so rays through shared edges count once. With
+// vote set rays are cast along all three axes and a voxel is filled when
+// at least two agree, which tolerates small holes and flipped triangles.
+func VoxelizeSolid(tris []Tri, oct *Octree, org Vec3, voxel float32, size Size,
+	v int, vote bool) {
+
+	local := make([]Tri, len(tris))
+	for i := range tris {
+		for k := range tris[i] {
+			local[i][k] = tris[i][k].Minus(org).Mul(1 / voxel)
+		}
+	}
+
+	m := parity(local, size, 2)
+	if vote {
+		mx, my := parity(local, size, 0), parity(local, size, 1)
+		for i := range m.bits {
+			a, b, c := mx.bits[i], my.bits[i], m.bits[i]
+			m.bits[i] = a&b | b&c | a&c
+		}
+	}
+
+	for z := 0; z < size.D; z++ {
+		for y := 0; y < size.H; y++ {
+			for x := 0; x < size.W; x++ {
+				if m.get(x, y, z) { oct.Set(x, y, z, v) }
+			}
+		}
+	}
+}
+
+// parity casts rays along axis through all voxel centers of the lattice
+// and marks the voxels between entry and exit crossings.
+func parity(tris []Tri, size Size, axis int) *mask {
+
+	dims := [3]int { size.W, size.H, size.D }
+	u, v := (axis+1) % 3, (axis+2) % 3
+	nu, nv := dims[u], dims[v]
+
+	comp := func(p Vec3, k int) float32 {
+		switch k {
+		case 0: return p.X
+		case 1: return p.Y
+		}
+		return p.Z
+	}
+
+	crossings := make([][]float32, nu*nv)
+	for i := range tris {
+		var p [3][3]float32
+		for k := 0; k < 3; k++ {
+			p[k] = [3]float32 { comp(tris[i][k], u), comp(tris[i][k], v), comp(tris[i][k], axis) }
+		}
+
+		// counter-clockwise in the (u, v) plane
+		area := (p[1][0]-p[0][0])*(p[2][1]-p[0][1]) - (p[2][0]-p[0][0])*(p[1][1]-p[0][1])
+		if area == 0 { continue }
+		if area < 0 { p[1], p[2] = p[2], p[1]; area = -area }
+
+		lo := func(k int) int {
+			return int(math.Ceil(float64(min32(p[0][k], min32(p[1][k], p[2][k])) - .5)))
+		}
+		hi := func(k int) int {
+			return int(math.Floor(float64(max32(p[0][k], max32(p[1][k], p[2][k])) - .5)))
+		}
+
+		i0, i1, j0, j1 := lo(0), hi(0), lo(1), hi(1)
+		if i0 < 0 { i0 = 0 }; if i1 >= nu { i1 = nu-1 }
+		if j0 < 0 { j0 = 0 }; if j1 >= nv { j1 = nv-1 }
+
+		for j := j0; j <= j1; j++ {
+			for i := i0; i <= i1; i++ {
+				cu, cv := float32(i) + .5, float32(j) + .5
+
+				var w [3]float32
+				in := true
+				for k := 0; k < 3 && in; k++ {
+					a, b := p[k], p[(k+1)%3]
+					w[k] = (b[0]-a[0])*(cv-a[1]) - (b[1]-a[1])*(cu-a[0])
+					in = w[k] > 0 || w[k] == 0 && topLeft(a, b)
+				}
+				if !in { continue }
+
+				// w[k] weighs the vertex opposite of edge k
+				t := (w[1]*p[0][2] + w[2]*p[1][2] + w[0]*p[2][2]) / area
+				crossings[j*nu + i] = append(crossings[j*nu + i], t)
+			}
+		}
+	}
+
+	m := newMask(0, 0, 0, size.W, size.H, size.D)
+	var c [3]int
+	for j := 0; j < nv; j++ {
+		for i := 0; i < nu; i++ {
+			ts := crossings[j*nu + i]
+			sort.Slice(ts, func(a, b int) bool { return ts[a] < ts[b] })
+
+			c[u], c[v] = i, j
+			for k := 0; k+1 < len(ts); k += 2 {
+				s0 := int(math.Ceil(float64(ts[k] - .5)))
+				s1 := int(math.Ceil(float64(ts[k+1] - .5)))
+				if s0 < 0 { s0 = 0 }; if s1 > dims[axis] { s1 = dims[axis] }
+				for s := s0; s < s1; s++ {
+					c[axis] = s
+					m.set(c[0], c[1], c[2])
+				}
+			}
+		}
+	}
+
+	return m
+}
+
+// topLeft reports whether the counter-clockwise edge a-b is a top or left
+// edge, the edges that own the sample points lying exactly on them.
+func topLeft(a, b [3]float32) bool {
+	return a[1] == b[1] && b[0] < a[0] || b[1] < a[1]
+}
@@ -0,0 +1,51 @@
+package glvox
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVoxelizeSolid(t *testing.T) {
+
+	// faces through voxel centers exercise the top-left rule
+	oct := NewOctree(16)
+	VoxelizeSolid(cubeTris(Vec3 { 2.5, 2.5, 2.5 }, 4), oct, Vec3 {}, 1, Size { 10, 10, 10 }, 1, false)
+	if n := count(OctreeVolume { oct }); n != 4*4*4 {
+		t.Errorf("64 voxels expected, was %d", n)
+	}
+	if v, _ := oct.Get(6, 6, 6); v != 0 {
+		t.Errorf("voxel outside the cube expected, was %d", v)
+	}
+
+	tris := sphereTris(Vec3 { 16, 16, 16 }, 12, 64)
+	exp := 4.0 / 3.0 * math.Pi * 12 * 12 * 12
+
+	oct = NewOctree(32)
+	VoxelizeSolid(tris, oct, Vec3 {}, 1, Size { 32, 32, 32 }, 1, false)
+	if n := float64(count(OctreeVolume { oct })); math.Abs(n - exp) > .02 * exp {
+		t.Errorf("about %.0f voxels expected, was %.0f", exp, n)
+	}
+}
+
+func TestVoxelizeSolidVote(t *testing.T) {
+
+	// a sphere with holes loses spans along z without voting
+	var tris []Tri
+	for i, tri := range sphereTris(Vec3 { 16, 16, 16 }, 12, 64) {
+		if i % 97 != 0 { tris = append(tris, tri) }
+	}
+	exp := 4.0 / 3.0 * math.Pi * 12 * 12 * 12
+
+	oct := NewOctree(32)
+	VoxelizeSolid(tris, oct, Vec3 {}, 1, Size { 32, 32, 32 }, 1, true)
+	if n := float64(count(OctreeVolume { oct })); math.Abs(n - exp) > .01 * exp {
+		t.Errorf("about %.0f voxels expected, was %.0f", exp, n)
+	}
+
+	// plain parity gets the same mesh wrong
+	oct = NewOctree(32)
+	VoxelizeSolid(tris, oct, Vec3 {}, 1, Size { 32, 32, 32 }, 1, false)
+	if n := float64(count(OctreeVolume { oct })); math.Abs(n - exp) <= .01 * exp {
+		t.Errorf("parity without voting expected to mislabel the leaky sphere, was %.0f of %.0f voxels", n, exp)
+	}
+}
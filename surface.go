@@ -0,0 +1,43 @@
+package glvox
+
+// VoxelizeSurface sets every voxel of oct that a triangle of tris touches
+// to v, including voxels only grazed at an edge or corner. Voxel (x, y, z)
+// covers the cube org + [x, x+1)*voxel and so on. The octree is walked top
+// down with the triangles overlapping each node, so nodes no triangle
+// touches are never split.
+func VoxelizeSurface(tris []Tri, oct *Octree, org Vec3, voxel float32, v int) {
+
+	type node struct {
+		x, y, z, size int
+		tris []int32
+	}
+
+	all := make([]int32, 0, len(tris))
+	for i := range tris { all = append(all, int32(i)) }
+
+	stack := []node { { 0, 0, 0, oct.Size, all } }
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		lo := org.Plus(Vec3 { float32(n.x), float32(n.y), float32(n.z) }.Mul(voxel))
+		hi := lo.Plus(Vec3 { 1, 1, 1 }.Mul(float32(n.size) * voxel))
+
+		var hit []int32
+		for _, i := range n.tris {
+			if tris[i].OverlapsBox(lo, hi) { hit = append(hit, i) }
+		}
+		if len(hit) == 0 { continue }
+
+		if n.size == 1 {
+			oct.Set(n.x, n.y, n.z, v)
+			continue
+		}
+
+		s := n.size / 2
+		for off := 0; off < 8; off++ {
+			stack = append(stack, node {
+				n.x + (off & 1) * s, n.y + (off >> 1 & 1) * s, n.z + (off >> 2) * s, s, hit })
+		}
+	}
+}
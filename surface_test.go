@@ -0,0 +1,28 @@
+package glvox
+
+import (
+	"testing"
+)
+
+func TestVoxelizeSurface(t *testing.T) {
+
+	// a cube with faces on voxel boundaries touches both adjacent layers
+	oct := NewOctree(16)
+	VoxelizeSurface(cubeTris(Vec3 { 4, 4, 4 }, 4), oct, Vec3 {}, 1, 1)
+	if n := count(OctreeVolume { oct }); n != 6*6*6 - 2*2*2 {
+		t.Errorf("%d voxels expected, was %d", 6*6*6 - 2*2*2, n)
+	}
+
+	// a thin sliver still marks the voxels it passes
+	oct = NewOctree(16)
+	tri := []Tri { { { .5, .5, .5 }, { 12.5, .5, .5 }, { 12.5, .51, .5 } } }
+	VoxelizeSurface(tri, oct, Vec3 {}, 1, 1)
+	if n := count(OctreeVolume { oct }); n != 13 {
+		t.Errorf("13 voxels expected, was %d", n)
+	}
+
+	// untouched nodes stay unsplit
+	if len(oct.Index) / 8 > 1 + 4*13 {
+		t.Errorf("at most %d nodes expected, was %d", 1 + 4*13, len(oct.Index) / 8)
+	}
+}
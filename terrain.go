@@ -0,0 +1,70 @@
+package glvox
+
+// Layer is a band of material below the terrain surface; a Depth of 0
+// reaches down to the bottom, hiding the layers after it.
+type Layer struct {
+	Depth int
+	Value int
+}
+
+// Terrain describes a heightmap terrain with caves, with y pointing up.
+type Terrain struct {
+	Seed int64
+
+	Height float64		// mean surface height in voxels
+	Amplitude float64	// surface height variation in voxels
+	Scale float64		// horizontal feature size in voxels, 0 for a flat surface
+	Fractal Fractal
+
+	// Layers are laid from the surface downwards; voxels below the last
+	// layer get its value.
+	Layers []Layer
+
+	// Caves are tunnels where two noise fields of feature size CaveScale
+	// both lie within CaveWidth of zero. A CaveWidth or CaveScale of 0
+	// disables them.
+	CaveScale float64
+	CaveWidth float64
+}
+
+// Generate writes the terrain into dst over the columns of size. Empty
+// voxels are not written.
+func (t *Terrain) Generate(dst Setter, size Size) {
+
+	if len(t.Layers) == 0 { return }
+
+	ground := NewNoise(t.Seed)
+	cave1, cave2 := NewNoise(t.Seed + 1), NewNoise(t.Seed + 2)
+	caves := Fractal { 2, 2, .5 }
+
+	for z := 0; z < size.D; z++ {
+		for x := 0; x < size.W; x++ {
+			h := int(t.Height)
+			if t.Scale > 0 {
+				fx, fz := float64(x) / t.Scale, float64(z) / t.Scale
+				h = int(t.Height + t.Amplitude * t.Fractal.FBM(ground.Simplex, fx, 0, fz))
+			}
+			if h > size.H { h = size.H }
+
+			layer, depth := 0, 0
+			for y := h-1; y >= 0; y-- {
+				for layer < len(t.Layers)-1 && t.Layers[layer].Depth > 0 && depth >= t.Layers[layer].Depth {
+					depth -= t.Layers[layer].Depth
+					layer++
+				}
+				depth++
+
+				if t.CaveWidth > 0 && t.CaveScale > 0 {
+					cx, cy, cz := float64(x) / t.CaveScale, float64(y) / t.CaveScale, float64(z) / t.CaveScale
+					if a := caves.FBM(cave1.Simplex, cx, cy, cz); a > -t.CaveWidth && a < t.CaveWidth {
+						if b := caves.FBM(cave2.Simplex, cx, cy, cz); b > -t.CaveWidth && b < t.CaveWidth {
+							continue
+						}
+					}
+				}
+
+				dst.Set(x, y, z, t.Layers[layer].Value)
+			}
+		}
+	}
+}
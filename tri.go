@@ -190,3 +190,104 @@ func solve(a, b, c, d, e, f float32) (s, t float32) {
 
 	return s, t
 }
+
+// Normal returns the unit normal, pointing to the side SqrDistance
+// treats as positive, or zero for a triangle without area.
+func (tri *Tri) Normal() Vec3 {
+	n := tri[1].Minus(tri[0]).Cross(tri[2].Minus(tri[0]))
+	if n.Dot(n) == 0 { return Vec3 {} }
+	return n.Normalize()
+}
+
+// Intersect returns the ray parameter t of the hit of ro + t*rd with the
+// triangle (Möller-Trumbore). Rays parallel to the triangle never hit.
+func (tri *Tri) Intersect(ro, rd Vec3) (t float32, hit bool) {
+
+	e1 := tri[1].Minus(tri[0])
+	e2 := tri[2].Minus(tri[0])
+
+	p := rd.Cross(e2)
+	det := e1.Dot(p)
+	if det == 0.0 { return }
+
+	inv := 1.0 / det
+	s := ro.Minus(tri[0])
+	u := s.Dot(p) * inv
+	if u < 0.0 || u > 1.0 { return }
+
+	q := s.Cross(e1)
+	v := rd.Dot(q) * inv
+	if v < 0.0 || u+v > 1.0 { return }
+
+	t = e2.Dot(q) * inv
+	hit = true
+	return
+}
+
+// Bounds returns the axis aligned bounding box of tris.
+func Bounds(tris []Tri) (lo, hi Vec3) {
+
+	if len(tris) == 0 { return }
+
+	lo, hi = tris[0][0], tris[0][0]
+	for i := range tris {
+		for _, p := range tris[i] {
+			lo = Vec3 { min32(lo.X, p.X), min32(lo.Y, p.Y), min32(lo.Z, p.Z) }
+			hi = Vec3 { max32(hi.X, p.X), max32(hi.Y, p.Y), max32(hi.Z, p.Z) }
+		}
+	}
+
+	return
+}
+
+func min32(a, b float32) float32 {
+	if a < b { return a }
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b { return a }
+	return b
+}
+
+func (tri *Tri) planeDistance(p Vec3) float32 {
+	return planeDistance(tri[0], tri[1].Minus(tri[0]), tri[2].Minus(tri[0]), p)
+}
+
+func (tri *Tri) degenerate() bool {
+	n := tri[1].Minus(tri[0]).Cross(tri[2].Minus(tri[0]))
+	return n.Dot(n) == 0
+}
+
+// OverlapsBox reports whether the triangle touches the box [lo, hi], by
+// the separating axis test of Akenine-Möller: the three box normals, the
+// triangle normal and the nine cross products of their edges.
+func (tri *Tri) OverlapsBox(lo, hi Vec3) bool {
+
+	c := lo.Plus(hi).Mul(.5)
+	h := hi.Minus(lo).Mul(.5)
+	v0, v1, v2 := tri[0].Minus(c), tri[1].Minus(c), tri[2].Minus(c)
+
+	// box normals
+	if min32(v0.X, min32(v1.X, v2.X)) > h.X || max32(v0.X, max32(v1.X, v2.X)) < -h.X { return false }
+	if min32(v0.Y, min32(v1.Y, v2.Y)) > h.Y || max32(v0.Y, max32(v1.Y, v2.Y)) < -h.Y { return false }
+	if min32(v0.Z, min32(v1.Z, v2.Z)) > h.Z || max32(v0.Z, max32(v1.Z, v2.Z)) < -h.Z { return false }
+
+	// edge cross products
+	edges := [3]Vec3 { v1.Minus(v0), v2.Minus(v1), v0.Minus(v2) }
+	units := [3]Vec3 { { 1, 0, 0 }, { 0, 1, 0 }, { 0, 0, 1 } }
+	for _, e := range edges {
+		for _, u := range units {
+			a := u.Cross(e)
+			p0, p1, p2 := a.Dot(v0), a.Dot(v1), a.Dot(v2)
+			r := h.X*abs(a.X) + h.Y*abs(a.Y) + h.Z*abs(a.Z)
+			if min32(p0, min32(p1, p2)) > r || max32(p0, max32(p1, p2)) < -r { return false }
+		}
+	}
+
+	// triangle plane
+	n := edges[0].Cross(edges[1])
+	r := h.X*abs(n.X) + h.Y*abs(n.Y) + h.Z*abs(n.Z)
+	d := n.Dot(v0)
+	return d <= r && d >= -r
+}
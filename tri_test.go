@@ -94,3 +94,39 @@ func TestSqrDistance(t *testing.T) {
 		}
 	}
 }
+
+func TestOverlapsBox(t *testing.T) {
+
+	lo, hi := glvox.Vec3{0.0, 0.0, 0.0}, glvox.Vec3{1.0, 1.0, 1.0}
+
+	tests := []struct {
+		tri glvox.Tri
+		exp bool
+	}{
+		// inside
+		{glvox.Tri{{0.2, 0.2, 0.5}, {0.8, 0.2, 0.5}, {0.2, 0.8, 0.5}}, true},
+		// box inside a big triangle
+		{glvox.Tri{{-10.0, -10.0, 0.5}, {10.0, -10.0, 0.5}, {0.0, 10.0, 0.5}}, true},
+		// touching a face
+		{glvox.Tri{{-1.0, 0.0, 1.0}, {2.0, 0.0, 1.0}, {0.0, 2.0, 1.0}}, true},
+		// above
+		{glvox.Tri{{-1.0, 0.0, 1.1}, {2.0, 0.0, 1.1}, {0.0, 2.0, 1.1}}, false},
+		// standing across the vertical edge at x = y = 1
+		{glvox.Tri{{1.6, 0.0, -1.0}, {0.0, 1.6, -1.0}, {0.8, 0.8, 3.0}}, true},
+		// the same moved past the edge, only its plane separates
+		{glvox.Tri{{2.2, 0.0, -1.0}, {0.0, 2.2, -1.0}, {1.1, 1.1, 3.0}}, false},
+		// cutting off the corner at (1, 1, 1)
+		{glvox.Tri{{1.5, 0.6, 0.6}, {0.6, 1.5, 0.6}, {0.6, 0.6, 1.5}}, true},
+		// an edge touching the vertical edge at x = y = 1 in a single point
+		{glvox.Tri{{3.0, -1.0, 0.95}, {-1.0, 3.0, 0.95}, {2.5, 2.5, 0.95}}, true},
+		// plane cuts the box but the triangle does not, only an edge axis
+		// separates
+		{glvox.Tri{{1.9, 1.0, 0.5}, {1.0, 1.9, 0.5}, {1.9, 1.9, 0.5}}, false},
+	}
+
+	for i, tt := range tests {
+		if got := tt.tri.OverlapsBox(lo, hi); got != tt.exp {
+			t.Errorf("test%d: overlap %v expected, was %v", i+1, tt.exp, got)
+		}
+	}
+}
@@ -129,3 +129,11 @@ func (m Mat3) Mul(v Vec3) (w Vec3) {
 	}
 	return w
 }
+
+func sqrt32(f float32) float32 {
+	return float32(math.Sqrt(float64(f)))
+}
+
+func (m Mat3) Transpose() Mat3 {
+	return Mat3 { m[0], m[3], m[6],  m[1], m[4], m[7],  m[2], m[5], m[8] }
+}
@@ -0,0 +1,128 @@
+package glvox
+
+// GridVolume adapts a Grid to the int based voxel interfaces.
+// Voxels outside the grid read as 0 and writes to them are dropped.
+type GridVolume struct {
+	*Grid
+}
+
+func (g GridVolume) Get(x, y, z int) (val, size int) {
+	size = 1
+	if x < 0 || x >= int(g.W) || y < 0 || y >= int(g.H) || z < 0 || z >= int(g.D) {
+		return
+	}
+	val = int(g.Grid.Get(int32(x), int32(y), int32(z)))
+	return
+}
+
+func (g GridVolume) Set(x, y, z int, v int) {
+	if x < 0 || x >= int(g.W) || y < 0 || y >= int(g.H) || z < 0 || z >= int(g.D) {
+		return
+	}
+	g.Grid.Set(int32(x), int32(y), int32(z), int32(v))
+}
+
+func (g GridVolume) Size() Size {
+	return Size { int(g.W), int(g.H), int(g.D) }
+}
+
+// OctreeVolume adapts an Octree to the Sized interface.
+type OctreeVolume struct {
+	*Octree
+}
+
+func (o OctreeVolume) Size() Size {
+	s := o.Octree.Size
+	return Size { s, s, s }
+}
+
+// octreeOf returns the octree behind v, if any.
+func octreeOf(v interface{}) *Octree {
+	switch o := v.(type) {
+	case *Octree: return o
+	case OctreeVolume: return o.Octree
+	}
+	return nil
+}
+
+// bounds returns the region of vol worth looking at. For octrees this is
+// the bounding box of all non-empty leaves, grown by pad and clipped.
+func bounds(vol SizedGetter, pad int) (x0, y0, z0, x1, y1, z1 int) {
+
+	s := vol.Size()
+	oct := octreeOf(vol)
+	if oct == nil { return 0, 0, 0, s.W, s.H, s.D }
+
+	x0, y0, z0 = s.W, s.H, s.D
+	oct.leaves(0, 0, 0, s.W, s.H, s.D, func(l leaf) {
+		if l.val == 0 { return }
+		if l.x < x0 { x0 = l.x }; if l.x+l.size > x1 { x1 = l.x+l.size }
+		if l.y < y0 { y0 = l.y }; if l.y+l.size > y1 { y1 = l.y+l.size }
+		if l.z < z0 { z0 = l.z }; if l.z+l.size > z1 { z1 = l.z+l.size }
+	})
+	if x0 >= x1 { return 0, 0, 0, 0, 0, 0 }
+
+	x0 -= pad; if x0 < 0 { x0 = 0 }
+	y0 -= pad; if y0 < 0 { y0 = 0 }
+	z0 -= pad; if z0 < 0 { z0 = 0 }
+	x1 += pad; if x1 > s.W { x1 = s.W }
+	y1 += pad; if y1 > s.H { y1 = s.H }
+	z1 += pad; if z1 > s.D { z1 = s.D }
+	return
+}
+
+// loadGrid copies the values of the box [x0,x1)×[y0,y1)×[z0,z1) of vol into
+// a grid. Octrees are read leaf by leaf, anything else voxel by voxel.
+func loadGrid(vol Getter, x0, y0, z0, x1, y1, z1 int) *Grid {
+
+	w, h, d := x1-x0, y1-y0, z1-z0
+	g := NewGrid(int32(w), int32(h), int32(d))
+
+	if oct := octreeOf(vol); oct != nil {
+		oct.leaves(x0, y0, z0, x1, y1, z1, func(l leaf) {
+			if l.val == 0 { return }
+			lx0, lx1 := l.x - x0, l.x + l.size - x0
+			ly0, ly1 := l.y - y0, l.y + l.size - y0
+			lz0, lz1 := l.z - z0, l.z + l.size - z0
+			if lx0 < 0 { lx0 = 0 }; if lx1 > w { lx1 = w }
+			if ly0 < 0 { ly0 = 0 }; if ly1 > h { ly1 = h }
+			if lz0 < 0 { lz0 = 0 }; if lz1 > d { lz1 = d }
+			for z := lz0; z < lz1; z++ {
+				for y := ly0; y < ly1; y++ {
+					row := g.data[(z*h + y)*w:]
+					for x := lx0; x < lx1; x++ { row[x] = int32(l.val) }
+				}
+			}
+		})
+		return g
+	}
+
+	for z := z0; z < z1; z++ {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				v, _ := vol.Get(x, y, z)
+				g.data[((z-z0)*h + y-y0)*w + x-x0] = int32(v)
+			}
+		}
+	}
+
+	return g
+}
+
+// setBox sets the box [x0,x1)×[y0,y1)×[z0,z1) of dst to v, at once if dst
+// is a BoxSetter.
+func setBox(dst Setter, x0, y0, z0, x1, y1, z1 int, v int) {
+
+	if b, ok := dst.(BoxSetter); ok {
+		b.SetBox(x0, y0, z0, x1, y1, z1, v)
+		return
+	}
+
+	for z := z0; z < z1; z++ {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				dst.Set(x, y, z, v)
+			}
+		}
+	}
+}
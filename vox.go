@@ -12,6 +12,12 @@ type Setter interface {
 	Set(x, y, z int, v int)
 }
 
+// BoxSetter is implemented by volumes that set a box of voxels
+// [x0,x1)×[y0,y1)×[z0,z1) faster than voxel by voxel.
+type BoxSetter interface {
+	SetBox(x0, y0, z0, x1, y1, z1 int, v int)
+}
+
 type GetSetter interface {
 	Getter
 	Setter
@@ -36,3 +42,8 @@ type Vox struct {
 	Value float32
 }
 
+type SizedGetSetter interface {
+	Sized
+	Getter
+	Setter
+}
@@ -0,0 +1,40 @@
+package glvox
+
+// VoxelizeMesh writes tris into dst on a lattice of size voxels of edge
+// length voxel whose corner is at org; voxel (x, y, z) covers the cube
+// org + [x, x+1)*voxel and so on. Every voxel center is classified by its
+// signed distance to the nearest triangle, negative behind the triangle
+// normals. With band <= 0 all voxels inside the mesh are set to v,
+// otherwise only those within band of the surface. Triangles are kept in
+// a bounding volume hierarchy, and along every row the distance found at
+// one voxel lets the following voxels it provably covers go unqueried.
+func VoxelizeMesh(tris []Tri, dst Setter, org Vec3, voxel float32, size Size,
+	band float32, v int) {
+
+	b := newBVH(tris)
+
+	for z := 0; z < size.D; z++ {
+		for y := 0; y < size.H; y++ {
+			for x := 0; x < size.W; {
+				p := org.Plus(Vec3 { float32(x) + .5, float32(y) + .5, float32(z) + .5 }.Mul(voxel))
+				d := b.nearest(p)
+				dist := sqrt32(abs(d))
+
+				// the surface is at least dist away, so the next k voxels
+				// keep the sign and lie at least dist - k*voxel away
+				k := 1
+				if band <= 0 {
+					for x+k < size.W && float32(k)*voxel < dist { k++ }
+					if d <= 0 {
+						for i := 0; i < k; i++ { dst.Set(x+i, y, z, v) }
+					}
+				} else {
+					if dist <= band { dst.Set(x, y, z, v) }
+					for x+k < size.W && dist - float32(k)*voxel > band { k++ }
+				}
+
+				x += k
+			}
+		}
+	}
+}
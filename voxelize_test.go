@@ -0,0 +1,67 @@
+package glvox
+
+import (
+	"math"
+	"testing"
+)
+
+// cubeTris returns the twelve outward facing triangles of the cube
+// org + [0, s]³.
+func cubeTris(org Vec3, s float32) (tris []Tri) {
+	v := func(c int) Vec3 { return mcCorner(c).Mul(s).Plus(org) }
+	quads := [][4]int { { 0, 2, 3, 1 }, { 4, 5, 7, 6 }, { 0, 1, 5, 4 }, { 2, 6, 7, 3 }, { 0, 4, 6, 2 }, { 1, 3, 7, 5 } }
+	for _, q := range quads {
+		tris = append(tris, Tri { v(q[0]), v(q[1]), v(q[2]) }, Tri { v(q[0]), v(q[2]), v(q[3]) })
+	}
+	return
+}
+
+// sphereTris returns a closed, outward facing uv sphere.
+func sphereTris(c Vec3, r float32, n int) (tris []Tri) {
+	p := func(i, j int) Vec3 {
+		th, ph := math.Pi * float64(i) / float64(n), 2 * math.Pi * float64(j) / float64(n)
+		return c.Plus(Vec3 {
+			float32(math.Sin(th) * math.Cos(ph)),
+			float32(math.Sin(th) * math.Sin(ph)),
+			float32(math.Cos(th)) }.Mul(r))
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			tris = append(tris, Tri { p(i, j), p(i+1, j), p(i+1, j+1) }, Tri { p(i, j), p(i+1, j+1), p(i, j+1) })
+		}
+	}
+	return
+}
+
+func TestVoxelizeMesh(t *testing.T) {
+
+	tris := cubeTris(Vec3 { 2, 2, 2 }, 4)
+
+	oct := NewOctree(16)
+	VoxelizeMesh(tris, oct, Vec3 {}, 1, Size { 10, 10, 10 }, 0, 1)
+	if n := count(OctreeVolume { oct }); n != 64 {
+		t.Errorf("64 voxels expected, was %d", n)
+	}
+	if v, _ := oct.Get(2, 5, 3); v != 1 {
+		t.Errorf("voxel inside the cube expected")
+	}
+
+	// half voxel resolution, one layer on either side of the surface
+	oct = NewOctree(32)
+	VoxelizeMesh(tris, oct, Vec3 {}, .5, Size { 20, 20, 20 }, .5, 1)
+	if n := count(OctreeVolume { oct }); n != 10*10*10 - 6*6*6 {
+		t.Errorf("%d voxels expected, was %d", 10*10*10 - 6*6*6, n)
+	}
+}
+
+func TestVoxelizeSphere(t *testing.T) {
+
+	tris := sphereTris(Vec3 { 16, 16, 16 }, 12, 64)
+	oct := NewOctree(32)
+	VoxelizeMesh(tris, oct, Vec3 {}, 1, Size { 32, 32, 32 }, 0, 1)
+
+	exp := 4.0 / 3.0 * math.Pi * 12 * 12 * 12
+	if n := float64(count(OctreeVolume { oct })); math.Abs(n - exp) > .02 * exp {
+		t.Errorf("about %.0f voxels expected, was %.0f", exp, n)
+	}
+}
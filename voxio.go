@@ -4,89 +4,212 @@ import (
 	"bufio"
 	"io"
 	"os"
+	"fmt"
 	"strings"
 	"strconv"
 	"errors"
 )
 
-func ReadBinvox(filename string, voxels GetSetter,
-	offx, offy, offz int) (err error) {
+// BinvoxHeader is the metadata of a binvox file. Size is W, H, D in voxels;
+// the model spans Translate + [0, Scale] along its longest side.
+type BinvoxHeader struct {
+	Version int
+	Size Size
+	Translate Vec3
+	Scale float32
+}
 
-	f, err := os.Open(filename)
-	if err != nil { return }
+var (
+	ErrBinvoxMagic = errors.New("not a binvox file")
+	ErrBinvoxVersion = errors.New("unsupported version")
+	ErrBinvoxHeader = errors.New("malformed header line")
+	ErrBinvoxDim = errors.New("malformed dim")
+	ErrBinvoxNoData = errors.New("header without data")
+	ErrBinvoxTooMany = errors.New("too many voxels")
+	ErrBinvoxTooFew = errors.New("too few voxels")
+	ErrBinvoxTruncated = errors.New("truncated run")
+)
 
-	buf := bufio.NewReader(f)
+// BinvoxError is a decoding error and where it occurred: the header line
+// counted from 1, or the byte offset of the run in the whole stream.
+type BinvoxError struct {
+	Err error
+	Line int
+	Offset int64
+}
 
-	var line string
-	line, err = buf.ReadString('\n')
-	if err != nil { return }
+func (e *BinvoxError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("binvox line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("binvox offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *BinvoxError) Unwrap() error { return e.Err }
+
+// BinvoxDecoder reads a binvox stream. The header is parsed up front, so
+// callers can place or scale the model before decoding the voxels.
+type BinvoxDecoder struct {
+	Header BinvoxHeader
+	buf *bufio.Reader
+	offset int64		// of the first run
+}
+
+// binvoxMaxDim bounds each dimension, so the voxel count cannot overflow.
+const binvoxMaxDim = 1 << 16
+
+// NewBinvoxDecoder reads the header lines of r up to and including "data".
+// Errors are of type *BinvoxError.
+func NewBinvoxDecoder(r io.Reader) (dec *BinvoxDecoder, err error) {
+
+	buf := bufio.NewReader(r)
+	dec = &BinvoxDecoder { buf: buf }
+	hdr := &dec.Header
+	hdr.Scale = 1
 
-	if !strings.HasPrefix(line, "#binvox") {
-		err = errors.New("not a binvox file")
-		return
+	fail := func(line int, e error) (*BinvoxDecoder, error) {
+		return nil, &BinvoxError { Err: e, Line: line }
 	}
 
-	w, h, d := 0, 0, 0
-	data := false
-	for {
+	// only the end of the stream means a header without data
+	line, err := buf.ReadString('\n')
+	dec.offset += int64(len(line))
+	if err != nil && err != io.EOF { return fail(1, err) }
+	if !strings.HasPrefix(line, "#binvox") { return fail(1, ErrBinvoxMagic) }
+	if err != nil { return fail(1, ErrBinvoxNoData) }
+
+	hdr.Version, err = strconv.Atoi(strings.TrimSpace(line[len("#binvox"):]))
+	if err != nil || hdr.Version != 1 { return fail(1, ErrBinvoxVersion) }
+
+	dim := false
+	for n := 2; ; n++ {
 		line, err = buf.ReadString('\n')
-		if strings.HasPrefix(line, "dim") {
-			dims := strings.Fields(line)
-			d, err = strconv.Atoi(dims[1])
-			if err != nil { return }
-			h, err = strconv.Atoi(dims[2])
-			if err != nil { return }
-			w, err = strconv.Atoi(dims[3])
-			if err != nil { return }
-		} else if strings.HasPrefix(line, "data") {
-			data = true
-			break;
+		dec.offset += int64(len(line))
+		if err == io.EOF { return fail(n, ErrBinvoxNoData) }
+		if err != nil { return fail(n, err) }
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 { continue }
+
+		switch fields[0] {
+		case "dim":
+			var dims [3]int
+			if len(fields) != 4 { return fail(n, ErrBinvoxDim) }
+			for i := range dims {
+				dims[i], err = strconv.Atoi(fields[i+1])
+				if err != nil || dims[i] <= 0 || dims[i] > binvoxMaxDim { return fail(n, ErrBinvoxDim) }
+			}
+			hdr.Size = Size { dims[2], dims[1], dims[0] }
+			dim = true
+		case "translate":
+			var t [3]float64
+			if len(fields) != 4 { return fail(n, ErrBinvoxHeader) }
+			for i := range t {
+				t[i], err = strconv.ParseFloat(fields[i+1], 32)
+				if err != nil { return fail(n, ErrBinvoxHeader) }
+			}
+			hdr.Translate = Vec3 { float32(t[0]), float32(t[1]), float32(t[2]) }
+		case "scale":
+			if len(fields) != 2 { return fail(n, ErrBinvoxHeader) }
+			f, err := strconv.ParseFloat(fields[1], 32)
+			if err != nil { return fail(n, ErrBinvoxHeader) }
+			hdr.Scale = float32(f)
+		case "data":
+			if !dim { return fail(n, ErrBinvoxDim) }
+			return dec, nil
 		}
 	}
+}
+
+// Decode sets the voxels of the stream into voxels, placed at
+// (offx, offy, offz). Successive runs of a value are joined and set as
+// rows, slices and slabs, in one call each if voxels is a BoxSetter.
+// Empty runs are skipped, so voxels must start empty there. The stream
+// must hold exactly W*H*D voxels; errors are of type *BinvoxError.
+func (dec *BinvoxDecoder) Decode(voxels Setter, offx, offy, offz int) (err error) {
 
-	if w == 0 && h == 0 && d == 0 {
-		err = errors.New("no dim header")
-		return
+	w, h := int64(dec.Header.Size.W), int64(dec.Header.Size.H)
+	total := w * h * int64(dec.Header.Size.D)
+	buf := dec.buf
+
+	// the joined run [start, n) of value val
+	var start, n int64
+	val := -1
+	flush := func() {
+		box := func(x0, y0, z0, x1, y1, z1 int64) {
+			setBox(voxels, offx + int(x0), offy + int(y0), offz + int(z0),
+				offx + int(x1), offy + int(y1), offz + int(z1), val)
+		}
+
+		// empty space is not written, mostly air needs no nodes
+		for a := start; a < n && val != 0; {
+			z, y, x := a / (w*h), a / w % h, a % w
+			switch {
+			case x > 0 || n - a < w:
+				k := w - x; if n - a < k { k = n - a }
+				box(x, y, z, x + k, y + 1, z + 1)
+				a += k
+			case y > 0 || n - a < w*h:
+				k := h - y; if (n - a) / w < k { k = (n - a) / w }
+				box(0, y, z, w, y + k, z + 1)
+				a += k * w
+			default:
+				k := (n - a) / (w*h)
+				box(0, 0, z, w, h, z + k)
+				a += k * w*h
+			}
+		}
+		start = n
 	}
 
-	if !data {
-		err = errors.New("no data")
-		return
+	fail := func(e error) error {
+		flush()
+		return &BinvoxError { Err: e, Offset: dec.offset }
 	}
 
-	x, y, z := 0, 0, 0
-	for {
-		var val, cnt byte
+	for ; ; dec.offset += 2 {
+		var v, cnt byte
 
-		val, err = buf.ReadByte()
-		if err != nil {
-			if err == io.EOF { err = nil }
-			return
+		v, err = buf.ReadByte()
+		if err == io.EOF {
+			if n < total { return fail(ErrBinvoxTooFew) }
+			flush()
+			return nil
 		}
+		if err != nil { return fail(err) }
 
 		cnt, err = buf.ReadByte()
-		if err != nil {
-			return
-		}
+		if err == io.EOF { return fail(ErrBinvoxTruncated) }
+		if err != nil { return fail(err) }
 
-		for i := 0; i < int(cnt); i++ {
-
-			voxels.Set(offx + x, offy + y, offz + z, int(val))
-/*			if val == 1 {
-				updateDensity(voxels, offx + x, offy + y, offz + z)
-			}
-*/
-			x++
-			if x >= w {
-				x = 0; y++
-				if y >= h {
-					y = 0; z++
-					if z >= d { /* error */ }
-				}
-			}
-		}
+		if n + int64(cnt) > total { return fail(ErrBinvoxTooMany) }
+		if int(v) != val { flush(); val = int(v) }
+		n += int64(cnt)
 	}
+}
+
+// DecodeBinvox reads a whole binvox stream into voxels, placed at
+// (offx, offy, offz), and returns its header. Like Decode it leaves
+// empty voxels untouched.
+func DecodeBinvox(r io.Reader, voxels Setter,
+	offx, offy, offz int) (hdr BinvoxHeader, err error) {
+
+	dec, err := NewBinvoxDecoder(r)
+	if err != nil { return }
+
+	hdr = dec.Header
+	err = dec.Decode(voxels, offx, offy, offz)
+	return
+}
+
+func ReadBinvox(filename string, voxels GetSetter,
+	offx, offy, offz int) (err error) {
+
+	f, err := os.Open(filename)
+	if err != nil { return }
+	defer f.Close()
 
+	_, err = DecodeBinvox(f, voxels, offx, offy, offz)
 	return
 }
 
@@ -100,3 +223,48 @@ func updateDensity(voxels GetSetter, x, y, z int) {
 		}
 	}
 }
+
+// WriteBinvox run length encodes vol as binvox, in the order ReadBinvox
+// reads it: x fastest, then y, then z. Values must fit in a byte.
+func WriteBinvox(w io.Writer, vol SizedGetter, translate Vec3, scale float32) (err error) {
+
+	s := vol.Size()
+	buf := bufio.NewWriter(w)
+
+	fmt.Fprintf(buf, "#binvox 1\ndim %d %d %d\n", s.D, s.H, s.W)
+	fmt.Fprintf(buf, "translate %g %g %g\nscale %g\ndata\n",
+		translate.X, translate.Y, translate.Z, scale)
+
+	val, cnt := 0, 0
+	flush := func() {
+		for cnt > 0 {
+			n := cnt; if n > 255 { n = 255 }
+			buf.WriteByte(byte(val))
+			buf.WriteByte(byte(n))
+			cnt -= n
+		}
+	}
+
+	for z := 0; z < s.D; z++ {
+		for y := 0; y < s.H; y++ {
+			for x := 0; x < s.W; {
+				v, size := vol.Get(x, y, z)
+				if v < 0 || v > 255 {
+					return fmt.Errorf("binvox: value %d at (%d, %d, %d) exceeds a byte", v, x, y, z)
+				}
+
+				// the rest of an aligned uniform block of the row
+				n := 1
+				if size > 1 { n = size - x % size }
+				if x + n > s.W { n = s.W - x }
+
+				if v != val { flush(); val = v }
+				cnt += n
+				x += n
+			}
+		}
+	}
+	flush()
+
+	return buf.Flush()
+}
@@ -1,8 +1,15 @@
 package glvox_test
 
 import (
+	"bytes"
+	"errors"
 	"github.com/shogg/glvox"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
 	"testing"
+	"testing/iotest"
 	"fmt"
 )
 
@@ -23,9 +30,9 @@ func TestReadBinvox(t *testing.T) {
 		t.Error("index size 642216 expected, was", indexCount)
 	}
 
-	longJump := int32(0)
-	for i := int32(0); i < int32(indexCount); i++ {
-		for j := int32(0); j < 8; j++ {
+	longJump := 0
+	for i := 0; i < indexCount; i++ {
+		for j := 0; j < 8; j++ {
 			idx := voxels.Index[i<<3 + j]
 			if idx <= 0 { continue; }
 
@@ -35,10 +42,10 @@ func TestReadBinvox(t *testing.T) {
 	}
 	fmt.Println("longest jump", longJump)
 
-	avgJump := int32(0)
-	jumpCount := int32(0)
-	for i := int32(0); i < int32(indexCount); i++ {
-		for j := int32(0); j < 8; j++ {
+	avgJump := 0
+	jumpCount := 0
+	for i := 0; i < indexCount; i++ {
+		for j := 0; j < 8; j++ {
 			idx := voxels.Index[i<<3 + j]
 			if idx <= 0 { continue; }
 
@@ -49,3 +56,281 @@ func TestReadBinvox(t *testing.T) {
 	avgJump /= jumpCount
 	fmt.Println("average jump", avgJump)
 }
+
+func TestWriteBinvox(t *testing.T) {
+
+	// an odd sized volume with long runs and a non-binary value
+	src := glvox.GridVolume{glvox.NewGrid(300, 3, 2)}
+	for x := 0; x < 280; x++ {
+		src.Set(x, 1, 1, 1)
+	}
+	src.Set(299, 2, 0, 7)
+
+	var buf bytes.Buffer
+	err := glvox.WriteBinvox(&buf, src, glvox.Vec3{1.5, -2, 0}, 0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(buf.Bytes(), []byte("#binvox 1\ndim 2 3 300\ntranslate 1.5 -2 0\nscale 0.25\ndata\n")) {
+		t.Errorf("unexpected header %q", buf.String()[:60])
+	}
+
+	f, err := ioutil.TempFile("", "glvox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(buf.Bytes())
+	f.Close()
+
+	dst := glvox.NewOctree(512)
+	if err := glvox.ReadBinvox(f.Name(), dst, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for z := 0; z < 2; z++ {
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 300; x++ {
+				exp, _ := src.Get(x, y, z)
+				if v, _ := dst.Get(x, y, z); v != exp {
+					t.Fatalf("(%d, %d, %d): expected %d, was %d", x, y, z, exp, v)
+				}
+			}
+		}
+	}
+
+	// octrees are read block by block
+	oct := glvox.NewOctree(64)
+	glvox.Generate(glvox.BoxSDF(glvox.Vec3{32, 32, 32}, glvox.Vec3{16, 16, 16}), oct, glvox.Vec3{}, 1, 1)
+	var again bytes.Buffer
+	if err := glvox.WriteBinvox(&again, glvox.OctreeVolume{oct}, glvox.Vec3{}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(again.Bytes()[bytes.Index(again.Bytes(), []byte("data\n"))+5:], []byte{1}); n != 32*32 {
+		t.Errorf("one run per row of the box expected, was %d", n)
+	}
+
+	if glvox.WriteBinvox(&again, glvox.GridVolume{glvox.NewGrid(1, 1, 1)}, glvox.Vec3{}, 1) != nil {
+		t.Error("no error expected")
+	}
+	big := glvox.GridVolume{glvox.NewGrid(1, 1, 1)}
+	big.Set(0, 0, 0, 256)
+	if glvox.WriteBinvox(&again, big, glvox.Vec3{}, 1) == nil {
+		t.Error("error expected for a value beyond a byte")
+	}
+}
+
+func TestDecodeBinvox(t *testing.T) {
+
+	src := glvox.GridVolume{glvox.NewGrid(4, 3, 2)}
+	src.Set(1, 2, 1, 1)
+	src.Set(3, 0, 0, 1)
+
+	var buf bytes.Buffer
+	if err := glvox.WriteBinvox(&buf, src, glvox.Vec3{0.5, 1, -3}, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	dec, err := glvox.NewBinvoxDecoder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := glvox.BinvoxHeader{1, glvox.Size{4, 3, 2}, glvox.Vec3{0.5, 1, -3}, 2}
+	if dec.Header != exp {
+		t.Errorf("header %v expected, was %v", exp, dec.Header)
+	}
+
+	dst := glvox.NewOctree(16)
+	if err := dec.Decode(dst, 10, 0, 5); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := dst.Get(11, 2, 6); v != 1 {
+		t.Error("voxel at offset (11, 2, 6) expected")
+	}
+	if v, _ := dst.Get(13, 0, 5); v != 1 {
+		t.Error("voxel at offset (13, 0, 5) expected")
+	}
+	if v, _ := dst.Get(1, 2, 1); v != 0 {
+		t.Error("no voxel at (1, 2, 1) expected")
+	}
+
+	if _, err := glvox.NewBinvoxDecoder(bytes.NewBufferString("ply\n")); err == nil {
+		t.Error("error expected for a file that is not binvox")
+	}
+}
+
+func TestBinvoxErrors(t *testing.T) {
+
+	hdr := "#binvox 1\ndim 1 1 4\ndata\n"
+	tests := []struct {
+		in  string
+		err error
+		pos int64
+	}{
+		{"ply\n", glvox.ErrBinvoxMagic, 1},
+		{"#binvox 2\ndim 1 1 1\ndata\n", glvox.ErrBinvoxVersion, 1},
+		{"#binvox 1\ndim 1 1\ndata\n", glvox.ErrBinvoxDim, 2},
+		{"#binvox 1\ndim 1 -1 1\ndata\n", glvox.ErrBinvoxDim, 2},
+		{"#binvox 1\ntranslate 0 0\ndim 1 1 1\ndata\n", glvox.ErrBinvoxHeader, 2},
+		{"#binvox 1\nscale 1\ndata\n", glvox.ErrBinvoxDim, 3},
+		{"#binvox 1\ndim 1 1 1\n", glvox.ErrBinvoxNoData, 3},
+		{hdr + "\x01\x03", glvox.ErrBinvoxTooFew, int64(len(hdr)) + 2},
+		{hdr + "\x01\x03\x00\x02", glvox.ErrBinvoxTooMany, int64(len(hdr)) + 2},
+		{hdr + "\x01\x04\x00", glvox.ErrBinvoxTruncated, int64(len(hdr)) + 2},
+	}
+
+	for _, test := range tests {
+		_, err := glvox.DecodeBinvox(bytes.NewBufferString(test.in), glvox.NewOctree(4), 0, 0, 0)
+		e, ok := err.(*glvox.BinvoxError)
+		if !ok || e.Err != test.err {
+			t.Errorf("%q: %v expected, was %v", test.in, test.err, err)
+			continue
+		}
+		pos := e.Offset
+		if e.Line > 0 {
+			pos = int64(e.Line)
+		}
+		if pos != test.pos {
+			t.Errorf("%q: error at %d expected, was %v", test.in, test.pos, err)
+		}
+	}
+
+	if _, err := glvox.DecodeBinvox(bytes.NewBufferString(hdr+"\x01\x04"), glvox.NewOctree(4), 0, 0, 0); err != nil {
+		t.Error(err)
+	}
+
+	// read errors other than the end of the stream are wrapped where they occur
+	errRead := errors.New("read failed")
+	for _, test := range []struct {
+		in   string
+		line int
+		off  int64
+	}{
+		{"", 1, 0},
+		{"#binvox 1\ndim", 2, 0},
+		{hdr + "\x01\x02\x00", 0, int64(len(hdr)) + 2},
+	} {
+		r := io.MultiReader(bytes.NewBufferString(test.in), iotest.ErrReader(errRead))
+		_, err := glvox.DecodeBinvox(r, glvox.NewOctree(4), 0, 0, 0)
+		e, ok := err.(*glvox.BinvoxError)
+		if !ok || !errors.Is(err, errRead) || e.Line != test.line || e.Offset != test.off {
+			t.Errorf("%q: read error at line %d, offset %d expected, was %v", test.in, test.line, test.off, err)
+		}
+	}
+}
+
+func FuzzDecodeBinvox(f *testing.F) {
+
+	f.Add([]byte("#binvox 1\ndim 2 2 2\ntranslate 0 0 0\nscale 1\ndata\n\x00\x04\x01\x04"))
+	f.Add([]byte("#binvox 1\ndim 1 1 1\ndata\n\x01"))
+	f.Add([]byte("#binvox 1\ndim 4 4\n"))
+	f.Add([]byte("#binvox 1\ndim 65536 65536 65536\ndata\n\x01\xff\x01\xff"))
+	f.Add([]byte("#binvox\n\n\ndata\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		oct := glvox.NewOctree(8)
+		_, err := glvox.DecodeBinvox(bytes.NewReader(data), oct, 0, 0, 0)
+		if _, ok := err.(*glvox.BinvoxError); err != nil && !ok {
+			t.Errorf("BinvoxError expected, was %T", err)
+		}
+	})
+}
+
+func TestDecodeBinvoxBoxes(t *testing.T) {
+
+	// solid slabs, whole rows and scattered voxels
+	src := glvox.GridVolume{glvox.NewGrid(37, 21, 13)}
+	for z := 0; z < 13; z++ {
+		for y := 0; y < 21; y++ {
+			for x := 0; x < 37; x++ {
+				switch {
+				case z >= 4 && z < 8, z == 9 && y < 10:
+					src.Set(x, y, z, 2)
+				case (x*7+y*3+z*5)%11 == 0:
+					src.Set(x, y, z, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := glvox.WriteBinvox(&buf, src, glvox.Vec3{}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	oct := glvox.NewOctree(64)
+	grid := glvox.GridVolume{glvox.NewGrid(40, 30, 20)}
+	for _, dst := range []glvox.Setter{oct, grid} {
+		if _, err := glvox.DecodeBinvox(bytes.NewReader(buf.Bytes()), dst, 3, 5, 7); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for z := 0; z < 13; z++ {
+		for y := 0; y < 21; y++ {
+			for x := 0; x < 37; x++ {
+				exp, _ := src.Get(x, y, z)
+				if v, _ := oct.Get(x+3, y+5, z+7); v != exp {
+					t.Fatalf("octree (%d, %d, %d): expected %d, was %d", x, y, z, exp, v)
+				}
+				if v, _ := grid.Get(x+3, y+5, z+7); v != exp {
+					t.Fatalf("grid (%d, %d, %d): expected %d, was %d", x, y, z, exp, v)
+				}
+			}
+		}
+	}
+
+	// empty runs leave what is there, so a whole empty stream adds no nodes
+	empty := glvox.NewOctree(64)
+	empty.Set(1, 2, 3, 4)
+	nodes := len(empty.Index)
+	var zero bytes.Buffer
+	glvox.WriteBinvox(&zero, glvox.GridVolume{glvox.NewGrid(37, 21, 13)}, glvox.Vec3{}, 1)
+	if _, err := glvox.DecodeBinvox(&zero, empty, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := empty.Get(1, 2, 3); v != 4 || len(empty.Index) != nodes {
+		t.Errorf("empty runs written: %d at (1, 2, 3), %d of %d nodes", v, len(empty.Index), nodes)
+	}
+}
+
+func TestSetBox(t *testing.T) {
+
+	oct := glvox.NewOctree(32)
+	ref := glvox.GridVolume{glvox.NewGrid(32, 32, 32)}
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		var lo, hi [3]int
+		for k := range lo {
+			lo[k], hi[k] = rnd.Intn(36)-2, rnd.Intn(36)-2
+		}
+		v := rnd.Intn(3)
+		oct.SetBox(lo[0], lo[1], lo[2], hi[0], hi[1], hi[2], v)
+		for z := lo[2]; z < hi[2]; z++ {
+			for y := lo[1]; y < hi[1]; y++ {
+				for x := lo[0]; x < hi[0]; x++ {
+					ref.Set(x, y, z, v)
+				}
+			}
+		}
+	}
+
+	for z := 0; z < 32; z++ {
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				exp, _ := ref.Get(x, y, z)
+				if v, _ := oct.Get(x, y, z); v != exp {
+					t.Fatalf("(%d, %d, %d): expected %d, was %d", x, y, z, exp, v)
+				}
+			}
+		}
+	}
+
+	oct.SetBox(0, 0, 0, 32, 32, 32, 4)
+	if v, _ := oct.Get(5, 6, 7); v != 4 || len(oct.Index) != 8 {
+		t.Errorf("only the root node of 4 expected, was %d with %d slots", v, len(oct.Index))
+	}
+}